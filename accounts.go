@@ -16,6 +16,11 @@ package oanda
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/santegoeds/oanda/analytics"
 )
 
 // Account represents an Oanda account.
@@ -40,6 +45,37 @@ func (a Account) String() string {
 		a.Currency)
 }
 
+// AccountProperty is a property of an account as reported in Account.PropertyName.
+type AccountProperty string
+
+const (
+	// NfaProperty marks accounts that are subject to NFA regulations.  NFA accounts can not hold
+	// hedged positions and must close trades in FIFO order.
+	NfaProperty AccountProperty = "NFA"
+)
+
+// HasProperty returns true if the account has property name.
+func (a Account) HasProperty(name AccountProperty) bool {
+	for _, p := range a.PropertyName {
+		if strings.EqualFold(p, string(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHedgingAllowed returns true if the account may hold long and short trades in the same
+// instrument at the same time.
+func (a Account) IsHedgingAllowed() bool {
+	return !a.HasProperty(NfaProperty)
+}
+
+// NAV returns the net asset value of the account in the account currency; i.e. the balance plus
+// the unrealized profit or loss of the open trades.
+func (a Account) NAV() float64 {
+	return a.Balance + a.UnrealizedPl
+}
+
 // Accounts returns a list with all the know accounts.
 func (c *Client) Accounts() ([]Account, error) {
 	v := struct {
@@ -60,3 +96,104 @@ func (c *Client) Account(accountId Id) (*Account, error) {
 	}
 	return &acc, nil
 }
+
+// AccountByName returns the first of the known accounts whose name matches name, ignoring
+// case.  An error is returned if there is no such account.
+func (c *Client) AccountByName(name string) (*Account, error) {
+	accs, err := c.Accounts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range accs {
+		if strings.EqualFold(accs[i].Name, name) {
+			return &accs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("No account named %q", name)
+}
+
+// NewAccount creates a new account with the specified home currency and returns it.  Only
+// environments that support account creation, such as Oanda's sandbox, accept the request; the
+// fxpractice and fxtrade environments respond with an ApiError.
+func (c *Client) NewAccount(currency string) (*Account, error) {
+	if currency == "" {
+		return nil, &ValidationError{Field: "currency", Reason: "A currency is required."}
+	}
+	data := url.Values{}
+	data.Set("currency", strings.ToUpper(currency))
+	v := struct {
+		AccountId Id `json:"accountId"`
+	}{}
+	if err := requestAndDecode(c, "POST", "/v1/accounts", data, &v); err != nil {
+		return nil, err
+	}
+	return c.Account(v.AccountId)
+}
+
+// NAV queries the Oanda servers for the net asset value of the selected account.
+func (c *Client) NAV() (float64, error) {
+	if c.accountId == 0 {
+		return 0, &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	acc, err := c.Account(c.accountId)
+	if err != nil {
+		return 0, err
+	}
+	return acc.NAV(), nil
+}
+
+// BalancePoint is the balance of an account immediately after a transaction.
+type BalancePoint struct {
+	TranId  Id
+	Time    Time
+	Balance float64
+}
+
+// String implements the fmt.Stringer interface.
+func (bp BalancePoint) String() string {
+	return fmt.Sprintf("BalancePoint{TranId: %d, Time: %s, Balance: %v}", bp.TranId, bp.Time,
+		bp.Balance)
+}
+
+// BalanceHistory is the balance of an account over time in chronological order.
+type BalanceHistory []BalancePoint
+
+// Balances returns the balances as a slice of float64 values.
+func (bh BalanceHistory) Balances() []float64 {
+	balances := make([]float64, len(bh))
+	for i, bp := range bh {
+		balances[i] = bp.Balance
+	}
+	return balances
+}
+
+// MaxDrawdown returns the largest decline of the balance from a peak to a subsequent trough as a
+// fraction of the peak.  See analytics.MaxDrawdown.
+func (bh BalanceHistory) MaxDrawdown() (maxDD float64, peakIdx, troughIdx int) {
+	return analytics.MaxDrawdown(bh.Balances())
+}
+
+// BalanceHistory returns the balance of the selected account after every transaction that
+// changed the balance between start and end, inclusive.
+func (c *Client) BalanceHistory(start, end time.Time) (BalanceHistory, error) {
+	events, err := c.eventHistory(start)
+	if err != nil {
+		return nil, err
+	}
+	history := BalanceHistory{}
+	for _, evt := range events {
+		if evt.Time().Time().After(end) {
+			break
+		}
+		if be, ok := evt.(interface {
+			AccountBalance() float64
+		}); ok {
+			history = append(history, BalancePoint{
+				TranId:  evt.TranId(),
+				Time:    evt.Time(),
+				Balance: be.AccountBalance(),
+			})
+		}
+	}
+	return history, nil
+}
@@ -15,7 +15,11 @@
 package oanda_test
 
 import (
+	"time"
+
 	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
 )
 
 type TestAccountSuite struct {
@@ -51,3 +55,93 @@ func (ts *TestAccountSuite) TestAccountApi(c *check.C) {
 	c.Assert(acc.MarginRate > 0, check.Equals, true)
 	c.Assert(acc.MarginUsed, check.Equals, 0.0)
 }
+
+type AccountSuite struct{}
+
+var _ = check.Suite(&AccountSuite{})
+
+func (s *AccountSuite) TestHasProperty(c *check.C) {
+	acc := oanda.Account{}
+	c.Assert(acc.HasProperty(oanda.NfaProperty), check.Equals, false)
+	c.Assert(acc.IsHedgingAllowed(), check.Equals, true)
+
+	acc.PropertyName = []string{"NFA"}
+	c.Assert(acc.HasProperty(oanda.NfaProperty), check.Equals, true)
+	c.Assert(acc.HasProperty("nfa"), check.Equals, true)
+	c.Assert(acc.IsHedgingAllowed(), check.Equals, false)
+}
+
+func (s *AccountSuite) TestBalanceHistory(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"transactions": [
+		{"id": 5, "type": "TRADE_CLOSE", "time": "1400000500000000", "accountBalance": 1040},
+		{"id": 4, "type": "FEE", "time": "1400000400000000", "accountBalance": 1050},
+		{"id": 3, "type": "ORDER_FILLED", "time": "1400000300000000", "accountBalance": 960},
+		{"id": 2, "type": "LIMIT_ORDER_CREATE", "time": "1400000200000000"},
+		{"id": 1, "type": "TRADE_CLOSE", "time": "1400000100000000", "accountBalance": 1200}]}`}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	start := time.Unix(1400000000, 0)
+	history, err := client.BalanceHistory(start, start.Add(450*time.Second))
+	c.Assert(err, check.IsNil)
+	c.Assert(history, check.HasLen, 3)
+	c.Assert(history.Balances(), check.DeepEquals, []float64{1200, 960, 1050})
+	c.Assert(history[1].TranId, check.Equals, oanda.Id(3))
+
+	dd, peak, trough := history.MaxDrawdown()
+	c.Assert(dd, check.Equals, 0.2)
+	c.Assert(history[peak].TranId, check.Equals, oanda.Id(1))
+	c.Assert(history[trough].TranId, check.Equals, oanda.Id(3))
+}
+
+func (s *AccountSuite) TestNAV(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"accountId": 1, "balance": 1000.5,
+		"unrealizedPl": -20.25}`}
+	client := newStubClient(c, &tr)
+
+	_, err := client.NAV()
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	client.SelectAccount(1)
+	nav, err := client.NAV()
+	c.Assert(err, check.IsNil)
+	c.Assert(nav, check.Equals, 980.25)
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/accounts/1")
+}
+
+func (s *AccountSuite) TestAccountByName(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"accounts": [
+		{"accountId": 1, "accountName": "Primary", "accountCurrency": "USD"},
+		{"accountId": 2, "accountName": "Swing", "accountCurrency": "EUR"}]}`}
+	client := newStubClient(c, &tr)
+
+	acc, err := client.AccountByName("swing")
+	c.Assert(err, check.IsNil)
+	c.Assert(acc.AccountId, check.Equals, oanda.Id(2))
+	c.Assert(acc.Currency, check.Equals, "EUR")
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/accounts")
+
+	_, err = client.AccountByName("scalping")
+	c.Assert(err, check.ErrorMatches, `No account named "scalping"`)
+}
+
+func (s *AccountSuite) TestNewAccount(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Bodies: map[string]string{
+		"/v1/accounts":   `{"username": "user", "password": "pass", "accountId": 3}`,
+		"/v1/accounts/3": `{"accountId": 3, "accountName": "Primary", "accountCurrency": "CHF"}`,
+	}}
+	client := newStubClient(c, &tr)
+
+	_, err := client.NewAccount("")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	acc, err := client.NewAccount("chf")
+	c.Assert(err, check.IsNil)
+	c.Assert(acc.AccountId, check.Equals, oanda.Id(3))
+	c.Assert(acc.Currency, check.Equals, "CHF")
+	c.Assert(tr.Requests, check.HasLen, 2)
+	c.Assert(tr.Requests[0].Method, check.Equals, "POST")
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/accounts")
+}
@@ -0,0 +1,24 @@
+package analytics
+
+// MaxDrawdown returns the largest decline from a peak to a subsequent trough in the equity
+// series values, as a fraction of the peak.  peakIdx and troughIdx are the indices of the peak
+// and the trough in values; both are -1 if the series never declines.  Peaks that are not
+// positive are ignored since a relative decline is undefined for them.
+func MaxDrawdown(values []float64) (maxDD float64, peakIdx, troughIdx int) {
+	peakIdx, troughIdx = -1, -1
+	curPeak := -1
+	for i, v := range values {
+		if curPeak < 0 || v > values[curPeak] {
+			curPeak = i
+			continue
+		}
+		peak := values[curPeak]
+		if peak <= 0 {
+			continue
+		}
+		if dd := (peak - v) / peak; dd > maxDD {
+			maxDD, peakIdx, troughIdx = dd, curPeak, i
+		}
+	}
+	return maxDD, peakIdx, troughIdx
+}
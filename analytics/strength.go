@@ -0,0 +1,33 @@
+package analytics
+
+import "math"
+
+// A PairReturn is the return of a currency pair, quoted as Base/Quote, over a period.
+type PairReturn struct {
+	Base   string
+	Quote  string
+	Return float64
+}
+
+// CurrencyStrength returns the strength of every currency in returns as the average return of
+// the pairs in which it appears.  The return of a pair counts as is for its base currency and
+// negated for its quote currency, since a pair rises when its base strengthens against its quote.
+// Returns that are NaN are ignored.
+func CurrencyStrength(returns []PairReturn) map[string]float64 {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, pr := range returns {
+		if math.IsNaN(pr.Return) {
+			continue
+		}
+		sums[pr.Base] += pr.Return
+		counts[pr.Base]++
+		sums[pr.Quote] -= pr.Return
+		counts[pr.Quote]++
+	}
+	strength := make(map[string]float64, len(sums))
+	for ccy, sum := range sums {
+		strength[ccy] = sum / float64(counts[ccy])
+	}
+	return strength
+}
@@ -0,0 +1,113 @@
+package analytics
+
+import "sync"
+
+// SyncWindow is a Window that is safe for concurrent use by multiple goroutines.
+type SyncWindow struct {
+	mtx sync.RWMutex
+	w   *Window
+}
+
+// NewSyncWindow returns a new SyncWindow able to hold up to capacity values.
+func NewSyncWindow(capacity int) *SyncWindow {
+	return &SyncWindow{w: NewWindow(capacity)}
+}
+
+// Len returns the number of values in the SyncWindow.
+func (sw *SyncWindow) Len() int {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Len()
+}
+
+// Cap returns the maximum number of values that the SyncWindow can hold.
+func (sw *SyncWindow) Cap() int {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Cap()
+}
+
+// Values returns a copy of the values, most recent value first.
+func (sw *SyncWindow) Values() []float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	values := make([]float64, sw.w.Len())
+	copy(values, sw.w.values)
+	return values
+}
+
+// Push adds values to the front of the SyncWindow.  See Window.Push for details.
+func (sw *SyncWindow) Push(val ...float64) *SyncWindow {
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	sw.w.Push(val...)
+	return sw
+}
+
+// Replace overwrites the most recent value of the SyncWindow.  See Window.Replace for details.
+func (sw *SyncWindow) Replace(val float64) *SyncWindow {
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	sw.w.Replace(val)
+	return sw
+}
+
+// Resize changes the capacity of the SyncWindow.  See Window.Resize for details.
+func (sw *SyncWindow) Resize(newCap int) *SyncWindow {
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	sw.w.Resize(newCap)
+	return sw
+}
+
+// Sum returns the sum of all values in the SyncWindow.
+func (sw *SyncWindow) Sum() float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Sum()
+}
+
+// Mean returns the arithmetic mean of the values in the SyncWindow or NaN if it is empty.
+func (sw *SyncWindow) Mean() float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Mean()
+}
+
+// SMA returns the simple moving average of the values in the SyncWindow.  See Window.SMA for
+// details.
+func (sw *SyncWindow) SMA() float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.SMA()
+}
+
+// EMA returns the exponential moving average of the values in the SyncWindow.  See Window.EMA
+// for details.
+func (sw *SyncWindow) EMA(period int) float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.EMA(period)
+}
+
+// RSI returns the relative strength index of the values in the SyncWindow.  See Window.RSI for
+// details.
+func (sw *SyncWindow) RSI() float64 {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.RSI()
+}
+
+// Window returns a copy of the underlying Window that can be used without synchronization.
+func (sw *SyncWindow) Window() *Window {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return sw.w.Clone()
+}
+
+// String implements the fmt.Stringer interface.
+func (sw *SyncWindow) String() string {
+	sw.mtx.RLock()
+	defer sw.mtx.RUnlock()
+	return "Sync" + sw.w.String()
+}
@@ -86,6 +86,16 @@ func (w *Window) Push(val ...float64) *Window {
 	return w
 }
 
+// Replace overwrites the most recent value of the Window with val.  If the Window is empty val is
+// pushed instead.
+func (w *Window) Replace(val float64) *Window {
+	if w.Len() == 0 {
+		return w.Push(val)
+	}
+	w.values[0] = val
+	return w
+}
+
 // Sum returns the sum of all values in the Window.
 func (w Window) Sum() float64 {
 	sum := 0.0
@@ -95,6 +105,125 @@ func (w Window) Sum() float64 {
 	return sum
 }
 
+// Mean returns the arithmetic mean of the values in the Window or NaN if the Window is empty.
+func (w Window) Mean() float64 {
+	if w.Len() == 0 {
+		return nan
+	}
+	return w.Sum() / float64(w.Len())
+}
+
+// SMA returns the simple moving average of the values in the Window.  Unlike Mean, SMA ignores NaN
+// values and divides by the number of values that are not NaN, in the same way as EMA and RSI.
+// SMA returns NaN if the Window holds no values other than NaN.
+func (w Window) SMA() float64 {
+	sum, n := 0.0, 0
+	for _, f := range w.values {
+		if !math.IsNaN(f) {
+			sum += f
+			n++
+		}
+	}
+	if n == 0 {
+		return nan
+	}
+	return sum / float64(n)
+}
+
+// EMA returns the exponential moving average of the values in the Window with smoothing factor
+// 2/(period+1).  The average is seeded with the oldest value and then updated with every more
+// recent value.  NaN values are ignored.  EMA returns NaN if period is less than one or if the
+// Window holds fewer than period values.
+func (w Window) EMA(period int) float64 {
+	values := w.chronological()
+	if period < 1 || len(values) < period {
+		return nan
+	}
+	alpha := 2 / float64(period+1)
+	ema := values[0]
+	for _, v := range values[1:] {
+		ema = alpha*v + (1-alpha)*ema
+	}
+	return ema
+}
+
+// rsiPeriod is the number of price changes over which RSI averages gains and losses.
+const rsiPeriod = 14
+
+// RSI returns the relative strength index of the values in the Window, most likely closing
+// prices, as a number between 0 and 100.  The average gain and loss are the means of the oldest
+// 14 price changes and are then smoothed with every more recent change as defined by Wilder.  NaN
+// values are ignored.  RSI returns NaN if the Window holds fewer than 15 values, and 50 if prices
+// did not change at all.
+func (w Window) RSI() float64 {
+	values := w.chronological()
+	if len(values) < rsiPeriod+1 {
+		return nan
+	}
+	change := func(i int) (gain, loss float64) {
+		d := values[i] - values[i-1]
+		if d > 0 {
+			return d, 0
+		}
+		return 0, -d
+	}
+
+	avgGain, avgLoss := 0.0, 0.0
+	for i := 1; i <= rsiPeriod; i++ {
+		gain, loss := change(i)
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= rsiPeriod
+	avgLoss /= rsiPeriod
+	for i := rsiPeriod + 1; i < len(values); i++ {
+		gain, loss := change(i)
+		avgGain = (avgGain*(rsiPeriod-1) + gain) / rsiPeriod
+		avgLoss = (avgLoss*(rsiPeriod-1) + loss) / rsiPeriod
+	}
+
+	if avgGain+avgLoss == 0 {
+		return 50
+	}
+	return 100 * avgGain / (avgGain + avgLoss)
+}
+
+// chronological returns the values of the Window other than NaN from the oldest to the most
+// recent value.
+func (w Window) chronological() []float64 {
+	values := make([]float64, 0, len(w.values))
+	for i := len(w.values) - 1; i >= 0; i-- {
+		if !math.IsNaN(w.values[i]) {
+			values = append(values, w.values[i])
+		}
+	}
+	return values
+}
+
+// Min returns the smallest value in the Window or NaN if the Window is empty.
+func (w Window) Min() float64 {
+	if w.Len() == 0 {
+		return nan
+	}
+	min := w.values[0]
+	for _, f := range w.values[1:] {
+		min = math.Min(min, f)
+	}
+	return min
+}
+
+// Max returns the largest value in the Window or NaN if the Window is empty.
+func (w Window) Max() float64 {
+	if w.Len() == 0 {
+		return nan
+	}
+	max := w.values[0]
+	for _, f := range w.values[1:] {
+		max = math.Max(max, f)
+	}
+	return max
+}
+
 // Slice returns a new Window that refers to a subrange of the original Window. Both Window's
 // share the underlying data.
 func (w Window) Slice(start, end int) *Window {
@@ -114,6 +243,23 @@ func (w Window) Slice(start, end int) *Window {
 	return &Window{w.values[start:end]}
 }
 
+// Resize changes the capacity of the Window to newCap.  The most recent values are preserved; if
+// the Window holds more than newCap values the oldest values are discarded.  A negative newCap is
+// treated as 0.
+func (w *Window) Resize(newCap int) *Window {
+	if newCap < 0 {
+		newCap = 0
+	}
+	n := w.Len()
+	if n > newCap {
+		n = newCap
+	}
+	values := make([]float64, n, newCap)
+	copy(values, w.values)
+	w.values = values
+	return w
+}
+
 func (w Window) Clone() *Window {
 	c := Window{
 		values: make([]float64, w.Len(), w.Cap()),
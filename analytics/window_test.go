@@ -1,6 +1,8 @@
 package analytics_test
 
 import (
+	"math"
+	"sync"
 	"testing"
 
 	"gopkg.in/check.v1"
@@ -41,3 +43,186 @@ func (ts *TestSuite) TestWindow(c *check.C) {
 		c.Assert(v, check.Equals, w.Values()[i])
 	}
 }
+
+func (ts *TestSuite) TestWindowResize(c *check.C) {
+	w := analytics.NewWindow(20)
+	for i := 1; i <= 20; i++ {
+		w.Push(float64(i))
+	}
+
+	w.Resize(10)
+	c.Assert(w.Cap(), check.Equals, 10)
+	c.Assert(w.Len(), check.Equals, 10)
+	for i, v := range []float64{20, 19, 18, 17, 16, 15, 14, 13, 12, 11} {
+		c.Assert(w.Values()[i], check.Equals, v)
+	}
+
+	w.Resize(12)
+	c.Assert(w.Cap(), check.Equals, 12)
+	c.Assert(w.Len(), check.Equals, 10)
+
+	w.Push(21, 22, 23)
+	c.Assert(w.Len(), check.Equals, 12)
+	for i, v := range []float64{23, 22, 21, 20, 19} {
+		c.Assert(w.Values()[i], check.Equals, v)
+	}
+	c.Assert(w.Values()[11], check.Equals, 12.0)
+
+	w.Resize(-1)
+	c.Assert(w.Cap(), check.Equals, 0)
+	c.Assert(w.Len(), check.Equals, 0)
+	w.Push(24)
+	c.Assert(w.Len(), check.Equals, 0)
+}
+
+func (ts *TestSuite) TestWindowMean(c *check.C) {
+	w := analytics.NewWindow(3)
+	c.Assert(math.IsNaN(w.Mean()), check.Equals, true)
+
+	w.Push(1, 2, 3, 4)
+	c.Assert(w.Mean(), check.Equals, 3.0)
+}
+
+func (ts *TestSuite) TestWindowSMA(c *check.C) {
+	w := analytics.NewWindow(4)
+	c.Assert(math.IsNaN(w.SMA()), check.Equals, true)
+
+	// A partially filled window is averaged over the values that it holds.
+	w.Push(1, 2)
+	c.Assert(w.SMA(), check.Equals, w.Mean())
+	c.Assert(w.SMA(), check.Equals, 1.5)
+
+	w.Push(math.NaN())
+	c.Assert(w.SMA(), check.Equals, 1.5)
+	c.Assert(math.IsNaN(w.Mean()), check.Equals, true)
+
+	// The window holds 5, 4, 3 and NaN.
+	w.Push(3, 4, 5)
+	c.Assert(w.Len(), check.Equals, 4)
+	c.Assert(w.SMA(), check.Equals, 4.0)
+
+	w.Push(6)
+	c.Assert(w.SMA(), check.Equals, 4.5)
+
+	w = analytics.NewWindow(2).Push(math.NaN(), math.NaN())
+	c.Assert(math.IsNaN(w.SMA()), check.Equals, true)
+}
+
+func (ts *TestSuite) TestWindowEMA(c *check.C) {
+	w := analytics.NewWindow(10)
+	c.Assert(math.IsNaN(w.EMA(3)), check.Equals, true)
+
+	w.Push(1, 2)
+	c.Assert(math.IsNaN(w.EMA(3)), check.Equals, true)
+	c.Assert(math.IsNaN(w.EMA(0)), check.Equals, true)
+
+	// With period 3 the smoothing factor is 0.5: 1, 1.5, 2.25, 3.125, 4.0625.
+	w.Push(3, 4, 5)
+	c.Assert(w.EMA(3), check.Equals, 4.0625)
+	c.Assert(w.EMA(1), check.Equals, 5.0)
+}
+
+func (ts *TestSuite) TestWindowRSI(c *check.C) {
+	w := analytics.NewWindow(20)
+	c.Assert(math.IsNaN(w.RSI()), check.Equals, true)
+
+	// Seven gains of 1 and seven losses of 0.5 average to a gain of 0.5 and a loss of 0.25.
+	price := 10.0
+	w.Push(price)
+	for i := 0; i < 7; i++ {
+		price++
+		w.Push(price)
+		price -= 0.5
+		w.Push(price)
+	}
+	c.Assert(w.Len(), check.Equals, 15)
+	c.Assert(math.Abs(w.RSI()-200.0/3) < 1e-9, check.Equals, true)
+
+	// A further gain of 1 smooths the averages to 7.5/14 and 3.25/14.
+	w.Push(price + 1)
+	c.Assert(math.Abs(w.RSI()-100*7.5/10.75) < 1e-9, check.Equals, true)
+
+	flat := analytics.NewWindow(15)
+	for i := 0; i < 15; i++ {
+		flat.Push(1)
+	}
+	c.Assert(flat.RSI(), check.Equals, 50.0)
+}
+
+func (ts *TestSuite) TestWindowMinMax(c *check.C) {
+	w := analytics.NewWindow(3)
+	c.Assert(math.IsNaN(w.Min()), check.Equals, true)
+	c.Assert(math.IsNaN(w.Max()), check.Equals, true)
+
+	w.Push(5, 1, 3, 2)
+	c.Assert(w.Min(), check.Equals, 1.0)
+	c.Assert(w.Max(), check.Equals, 3.0)
+}
+
+func (ts *TestSuite) TestWindowReplace(c *check.C) {
+	w := analytics.NewWindow(3)
+	w.Replace(1.0)
+	c.Assert(w.Values(), check.DeepEquals, []float64{1})
+
+	w.Push(2.0).Replace(3.0)
+	c.Assert(w.Values(), check.DeepEquals, []float64{3, 1})
+}
+
+func (ts *TestSuite) TestSyncWindow(c *check.C) {
+	sw := analytics.NewSyncWindow(10)
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			sw.Push(float64(i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			sw.Mean()
+			sw.Values()
+		}
+	}()
+	wg.Wait()
+
+	c.Assert(sw.Len(), check.Equals, 10)
+	c.Assert(sw.Values()[0], check.Equals, 999.0)
+	c.Assert(sw.Mean(), check.Equals, 994.5)
+
+	values := sw.Values()
+	values[0] = 0
+	c.Assert(sw.Values()[0], check.Equals, 999.0)
+}
+
+func (ts *TestSuite) TestMaxDrawdown(c *check.C) {
+	dd, peak, trough := analytics.MaxDrawdown(nil)
+	c.Assert(dd, check.Equals, 0.0)
+	c.Assert(peak, check.Equals, -1)
+	c.Assert(trough, check.Equals, -1)
+
+	dd, peak, trough = analytics.MaxDrawdown([]float64{100, 110, 120})
+	c.Assert(dd, check.Equals, 0.0)
+	c.Assert(peak, check.Equals, -1)
+	c.Assert(trough, check.Equals, -1)
+
+	dd, peak, trough = analytics.MaxDrawdown([]float64{100, 120, 90, 110, 130, 104, 140})
+	c.Assert(dd, check.Equals, 0.25)
+	c.Assert(peak, check.Equals, 1)
+	c.Assert(trough, check.Equals, 2)
+}
+
+func (ts *TestSuite) TestCurrencyStrength(c *check.C) {
+	strength := analytics.CurrencyStrength([]analytics.PairReturn{
+		{Base: "EUR", Quote: "USD", Return: 0.02},
+		{Base: "USD", Quote: "JPY", Return: 0.01},
+		{Base: "EUR", Quote: "JPY", Return: 0.03},
+		{Base: "GBP", Quote: "USD", Return: math.NaN()},
+	})
+	c.Assert(strength, check.HasLen, 3)
+	c.Assert(math.Abs(strength["EUR"]-0.025) < 1e-12, check.Equals, true)
+	c.Assert(math.Abs(strength["USD"]-(-0.005)) < 1e-12, check.Equals, true)
+	c.Assert(math.Abs(strength["JPY"]-(-0.02)) < 1e-12, check.Equals, true)
+}
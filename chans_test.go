@@ -0,0 +1,48 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"sort"
+
+	"gopkg.in/check.v1"
+)
+
+type ChansSuite struct{}
+
+var _ = check.Suite(&ChansSuite{})
+
+func (s *ChansSuite) TestTickChansInstruments(c *check.C) {
+	tc := newTickChans([]string{"EUR_USD", "USD_JPY", "GBP_USD"})
+	instruments := tc.Instruments()
+	sort.Strings(instruments)
+	c.Assert(instruments, check.DeepEquals, []string{"EUR_USD", "GBP_USD", "USD_JPY"})
+
+	c.Assert(newTickChans(nil).Instruments(), check.HasLen, 0)
+}
+
+func (s *ChansSuite) TestEventChansAccountIds(c *check.C) {
+	ec := newEventChans(Ids{3, 1, 2})
+	accountIds := ec.AccountIds()
+	c.Assert(accountIds, check.HasLen, 3)
+	seen := make(map[Id]bool)
+	for _, accId := range accountIds {
+		c.Assert(accId, check.Not(check.Equals), Id(0))
+		seen[accId] = true
+	}
+	c.Assert(seen, check.DeepEquals, map[Id]bool{1: true, 2: true, 3: true})
+
+	c.Assert(newEventChans(nil).AccountIds(), check.HasLen, 0)
+}
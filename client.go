@@ -15,21 +15,26 @@
 package oanda
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	defaultDateFormat  = DateFormat("UNIX")
 	defaultContentType = ContentType("application/x-www-form-urlencoded")
+	jsonContentType    = ContentType("application/json")
 )
 
 var (
@@ -99,6 +104,13 @@ func (c ContentType) modify(req *http.Request) {
 type Client struct {
 	reqMods   []requestModifier
 	accountId Id
+	traceLog  *log.Logger
+	convMtx   sync.Mutex
+	convCtx   *ConversionContext
+	instMtx   sync.Mutex
+	instCache map[string]InstrumentInfo
+	clk       Clock
+	timeout   time.Duration
 	*http.Client
 }
 
@@ -108,43 +120,168 @@ func (c *Client) AccountId() Id { return c.accountId }
 // token should be set to the generated personal access token.
 //
 // See http://developer.oanda.com/docs/v1/auth/ for further information.
-func NewFxPracticeClient(token string) (*Client, error) {
+func NewFxPracticeClient(token string, opts ...ClientOption) (*Client, error) {
 	if token == "" {
-		return nil, errors.New("No FxPractice access token")
+		return nil, &ValidationError{Field: "token", Reason: "No FxPractice access token"}
 	}
-	return NewClient("fxpractice", token, nil)
+	return NewClient("fxpractice", token, nil, opts...)
 }
 
 // NewFxTradeClient returns a client instance that connects to Oanda's fxtrade environment. String token
 // should be set to the generated personal access token.
 //
 // See http://developer.oanda.com/docs/v1/auth/ for further information.
-func NewFxTradeClient(token string) (*Client, error) {
+func NewFxTradeClient(token string, opts ...ClientOption) (*Client, error) {
 	if token == "" {
-		return nil, errors.New("No FxTrade access token")
+		return nil, &ValidationError{Field: "token", Reason: "No FxTrade access token"}
 	}
-	return NewClient("fxtrade", token, nil)
+	return NewClient("fxtrade", token, nil, opts...)
 }
 
-func NewClient(environment string, token string, httpClient *http.Client) (*Client, error) {
+// NewClient returns a client instance that connects to the specified Oanda environment, either
+// "fxpractice" or "fxtrade".  The Oanda servers are not contacted unless option
+// WithValidateToken is given.
+func NewClient(environment string, token string, httpClient *http.Client,
+	opts ...ClientOption) (*Client, error) {
+
 	if httpClient == nil {
 		httpClient = DefaultHttpClient
 	}
 
+	co := clientOptions{}
+	for _, opt := range opts {
+		opt.applyClientOption(&co)
+	}
+
 	switch environment {
-	case "fxpractice":
-		return newClient(httpClient, Environment("fxpractice"), TokenAuthenticator(token)), nil
-	case "fxtrade":
-		return newClient(httpClient, Environment("fxtrade"), TokenAuthenticator(token)), nil
+	case "fxpractice", "fxtrade":
+	default:
+		return nil, &ValidationError{
+			Field:  "environment",
+			Reason: fmt.Sprintf("Invalid Oanda environment %v", environment),
+		}
+	}
+
+	if co.validateToken {
+		if err := ValidateTokenFormat(token); err != nil {
+			return nil, err
+		}
+	}
+
+	c := newClient(httpClient, Environment(environment), TokenAuthenticator(token))
+	c.timeout = co.requestTimeout
+	if co.validateToken {
+		if err := c.Ping(); err != nil {
+			if apiErr, ok := err.(*ApiError); ok {
+				return nil, &ValidationError{
+					Field: "token",
+					Reason: fmt.Sprintf("Access token not accepted by the %s environment: %s  "+
+						"Tokens are specific to an environment.", environment, apiErr.Message),
+				}
+			}
+			return nil, err
+		}
 	}
+	return c, nil
+}
+
+// A ClientOption is an optional argument for NewClient, NewFxPracticeClient and NewFxTradeClient.
+type ClientOption interface {
+	applyClientOption(*clientOptions)
+}
+
+type clientOptions struct {
+	validateToken  bool
+	requestTimeout time.Duration
+}
 
-	return nil, fmt.Errorf("Invalid Oanda environment %v", environment)
+type validateTokenOption struct{}
+
+func (validateTokenOption) applyClientOption(co *clientOptions) { co.validateToken = true }
+
+// WithValidateToken makes the client constructors check the format of the access token and verify
+// with a Ping that the Oanda servers accept it, so that a bad token is reported at construction
+// rather than by the first request.
+func WithValidateToken() ClientOption {
+	return validateTokenOption{}
+}
+
+type requestTimeoutOption time.Duration
+
+func (o requestTimeoutOption) applyClientOption(co *clientOptions) {
+	co.requestTimeout = time.Duration(o)
+}
+
+// WithRequestTimeout sets a default deadline of d for every request that is not a stream, such
+// as the requests of the Poll methods.  The deadline applies to sending the request and reading
+// the response; it is not applied to requests whose context already has a deadline.  A request
+// that exceeds the deadline fails with an error that wraps context.DeadlineExceeded.  Streams are
+// not affected.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return requestTimeoutOption(d)
+}
+
+// Personal access tokens consist of two 32 character hexadecimal strings joined by a dash.
+const (
+	tokenPartLen = 32
+	tokenLen     = 2*tokenPartLen + 1
+)
+
+// ValidateTokenFormat checks that token looks like a personal access token as generated on the
+// Oanda website without contacting the Oanda servers.
+func ValidateTokenFormat(token string) error {
+	invalid := func(reason string) error {
+		return &ValidationError{
+			Field: "token",
+			Reason: reason + "  Expected a personal access token of the form " +
+				"<32 hex digits>-<32 hex digits>; see http://developer.oanda.com/docs/v1/auth/.",
+		}
+	}
+
+	if token == "" {
+		return invalid("No access token.")
+	}
+	for i, r := range token {
+		switch {
+		case r == '-' && i == tokenPartLen:
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'f', r >= 'A' && r <= 'F':
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			return invalid(fmt.Sprintf("Access token contains whitespace at position %d.", i))
+		default:
+			return invalid(fmt.Sprintf("Access token contains invalid character %q at position %d.", r, i))
+		}
+	}
+	if len(token) != tokenLen {
+		return invalid(fmt.Sprintf("Access token has %d characters instead of %d.", len(token), tokenLen))
+	}
+	return nil
+}
+
+// Ping verifies that the Oanda servers can be reached and accept the access token of the client.
+func (c *Client) Ping() error {
+	_, err := c.Accounts()
+	return err
 }
 
 // SelectAccount configures an Oanda account.  All trades and orders will be booked under the
 // selected account.   Use AccountId 0 to disable account selection.
 func (c *Client) SelectAccount(accountId Id) {
 	c.accountId = accountId
+
+	c.convMtx.Lock()
+	c.convCtx = nil
+	c.convMtx.Unlock()
+
+	c.instMtx.Lock()
+	c.instCache = nil
+	c.instMtx.Unlock()
+}
+
+// SetTraceLogger configures a logger that receives the method, URL, response status and duration
+// of every request that the client executes.  The Authorization header is never logged.  Use nil
+// to disable tracing.
+func (c *Client) SetTraceLogger(l *log.Logger) {
+	c.traceLog = l
 }
 
 // NewRequest creates a new http request.
@@ -159,6 +296,24 @@ func (c *Client) NewRequest(method, urlStr string, body io.Reader) (*http.Reques
 	return req, nil
 }
 
+// NewJSONRequest creates a new http request with body, if not nil, encoded as a JSON document.
+func (c *Client) NewJSONRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+	var rdr io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rdr = bytes.NewReader(data)
+	}
+	req, err := c.NewRequest(method, urlStr, rdr)
+	if err != nil {
+		return nil, err
+	}
+	jsonContentType.modify(req)
+	return req, nil
+}
+
 // CancelRequest aborts an in-progress HTTP request.
 func (c *Client) CancelRequest(req *http.Request) {
 	type canceler interface {
@@ -190,9 +345,47 @@ type PollRequest struct {
 	req *http.Request
 }
 
-// Poll repeats the http request with which PollRequest was created.
+// NewPollRequest returns a PollRequest that repeatedly gets urlStr.
+func (c *Client) NewPollRequest(urlStr string) (*PollRequest, error) {
+	req, err := c.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &PollRequest{c, req}, nil
+}
+
+// URL returns a copy of the URL to which the next poll is sent.
+func (pr *PollRequest) URL() *url.URL {
+	u := *pr.req.URL
+	return &u
+}
+
+// String returns the method, URL and headers of the next poll with the access token redacted,
+// including the If-None-Match header that is set from the ETag of the previous response.
+func (pr *PollRequest) String() string {
+	h := redactedHeader(pr.req.Header)
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hdrs := make([]string, len(keys))
+	for i, k := range keys {
+		hdrs[i] = k + ": " + strings.Join(h[k], ",")
+	}
+	return fmt.Sprintf("%s %s [%s]", pr.req.Method, pr.req.URL, strings.Join(hdrs, "; "))
+}
+
+// Poll repeats the http request with which PollRequest was created.  The default request deadline
+// of the Client does not apply to Poll since the caller reads the response; it does apply to
+// PollAndDecode.
 func (pr *PollRequest) Poll() (*http.Response, error) {
-	rsp, err := pr.c.Do(pr.req)
+	return pr.poll(pr.req)
+}
+
+func (pr *PollRequest) poll(req *http.Request) (*http.Response, error) {
+	rsp, err := pr.c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -203,6 +396,68 @@ func (pr *PollRequest) Poll() (*http.Response, error) {
 	return rsp, nil
 }
 
+// PollAndDecode repeats the http request and decodes the response into v.  The result is false,
+// and v is left unchanged, if the resource was not modified since the previous poll; i.e. if
+// Oanda responds with 304 Not Modified or with an empty body.
+func (pr *PollRequest) PollAndDecode(v interface{}) (modified bool, err error) {
+	req, cancel := pr.c.withTimeout(pr.req)
+	defer cancel()
+	rsp, err := pr.poll(req)
+	if err != nil {
+		return false, err
+	}
+	defer closeResponse(rsp.Body)
+	if rsp.StatusCode == http.StatusNotModified || rsp.ContentLength == 0 {
+		return false, nil
+	}
+
+	dec := json.NewDecoder(rsp.Body)
+	if rsp.StatusCode >= 400 {
+		apiErr := ApiError{}
+		if err = dec.Decode(&apiErr); err != nil {
+			return false, err
+		}
+		return false, &apiErr
+	}
+	if err = dec.Decode(v); err != nil {
+		if err == io.EOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// do executes req and traces the request if a trace logger is configured.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.traceLog == nil {
+		return c.Do(req)
+	}
+
+	start := time.Now()
+	rsp, err := c.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		c.traceLog.Printf("%s %s error: %v (%v)", req.Method, req.URL, err, elapsed)
+	} else {
+		c.traceLog.Printf("%s %s %s (%v)", req.Method, req.URL, rsp.Status, elapsed)
+	}
+	return rsp, err
+}
+
+// redactedHeader returns a copy of header h in which the Authorization token is masked so that
+// the header can safely be logged.
+func redactedHeader(h http.Header) http.Header {
+	rh := make(http.Header, len(h))
+	for k, v := range h {
+		rh[k] = v
+	}
+	if rh.Get("Authorization") != "" {
+		rh.Set("Authorization", "Bearer [REDACTED]")
+	}
+	return rh
+}
+
 func newClient(httpClient *http.Client, reqMod ...requestModifier) *Client {
 	c := Client{
 		reqMods: []requestModifier{
@@ -226,11 +481,27 @@ type ApiError struct {
 	MoreInfo string `json:"moreInfo"`
 }
 
+// ValidationError is returned when the arguments of a request fail client-side validation and the
+// request is therefore not sent to Oanda.  Field names the offending argument.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (ve *ValidationError) Error() string {
+	return "ArgumentError: " + ve.Reason
+}
+
 func (ae *ApiError) Error() string {
 	return fmt.Sprintf("ApiError{Code: %d, Message: %s, Moreinfo: %s}",
 		ae.Code, ae.Message, ae.MoreInfo)
 }
 
+// IsMarketHalted returns true if the error reports that trading in an instrument is halted.
+func (ae *ApiError) IsMarketHalted() bool {
+	return strings.Contains(strings.ToLower(ae.Message), "halted")
+}
+
 func getAndDecode(c *Client, urlStr string, v interface{}) error {
 	return requestAndDecode(c, "GET", urlStr, nil, v)
 }
@@ -250,15 +521,43 @@ func requestAndDecode(c *Client, method, urlStr string, data url.Values, v inter
 		return err
 	}
 
-	debug("request %v\n", req)
+	debug("request %s %s %v\n", req.Method, req.URL, redactedHeader(req.Header))
 	debug("request data %v\n", data)
 
-	rsp, err := c.Do(req)
+	return doAndDecode(c, req, v)
+}
+
+// withTimeout returns req with the default request deadline of the Client applied, together
+// with the function that releases the deadline once the response has been read.
+func (c *Client) withTimeout(req *http.Request) (*http.Request, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return req, func() {}
+	}
+	if _, ok := req.Context().Deadline(); ok {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	return req.WithContext(ctx), cancel
+}
+
+func doAndDecode(c *Client, req *http.Request, v interface{}) error {
+	req, cancel := c.withTimeout(req)
+	defer cancel()
+	rsp, err := c.do(req)
 	if err != nil {
 		return err
 	}
 	defer closeResponse(rsp.Body)
+	return decodeResponse(rsp, v)
+}
 
+// decodeResponse decodes the body of a successful response into v or returns the ApiError that
+// the body of a failed response holds.  Success is decided by the HTTP status code alone: v need
+// not embed an ApiError, a code field in a successful response is ignored whatever its value, and
+// a failed response without a code field yields an ApiError with Code 0.
+// v may be any value that encoding/json can decode into, such as the slice of the Forex Labs
+// Calendar.
+func decodeResponse(rsp *http.Response, v interface{}) error {
 	debug("response %v", rsp)
 
 	var body io.Reader = rsp.Body
@@ -268,11 +567,19 @@ func requestAndDecode(c *Client, method, urlStr string, data url.Values, v inter
 
 	dec := json.NewDecoder(body)
 	if rsp.StatusCode < 400 {
-		return dec.Decode(v)
+		// A successful response without a body leaves v unchanged.
+		if rsp.StatusCode == http.StatusNoContent || rsp.ContentLength == 0 {
+			return nil
+		}
+		err := dec.Decode(v)
+		if err == io.EOF {
+			return nil
+		}
+		return err
 	}
 
 	apiErr := ApiError{}
-	if err = dec.Decode(&apiErr); err != nil {
+	if err := dec.Decode(&apiErr); err != nil {
 		return err
 	}
 	return &apiErr
@@ -0,0 +1,297 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+// stubResponse is a canned response returned by a stubTransport.
+type stubResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+// stubTransport is an http.RoundTripper that returns a canned response for every request.  The
+// first requests are answered from Script until it is exhausted.  Thereafter the response body is
+// taken from BodyFunc if it is set, from Bodies if it holds an entry for the request's URL path and
+// from Body otherwise.
+type stubTransport struct {
+	StatusCode int
+	Body       string
+	Bodies     map[string]string
+	BodyFunc   func(req *http.Request) string
+	Script     []stubResponse
+
+	mtx      sync.Mutex
+	Requests []*http.Request
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.Requests = append(t.Requests, req)
+
+	next := stubResponse{StatusCode: t.StatusCode, Header: make(http.Header), Body: t.Body}
+	if len(t.Script) > 0 {
+		next, t.Script = t.Script[0], t.Script[1:]
+		if next.Header == nil {
+			next.Header = make(http.Header)
+		}
+	} else if t.BodyFunc != nil {
+		next.Body = t.BodyFunc(req)
+	} else if body, ok := t.Bodies[req.URL.Path]; ok {
+		next.Body = body
+	}
+	body := next.Body
+	rsp := http.Response{
+		Status:     http.StatusText(next.StatusCode),
+		StatusCode: next.StatusCode,
+		Header:     next.Header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+	rsp.ContentLength = int64(len(body))
+	return &rsp, nil
+}
+
+func newStubClient(c *check.C, tr *stubTransport) *oanda.Client {
+	client, err := oanda.NewClient("fxpractice", "secret-token", &http.Client{Transport: tr})
+	c.Assert(err, check.IsNil)
+	return client
+}
+
+type ClientSuite struct{}
+
+var _ = check.Suite(&ClientSuite{})
+
+func (s *ClientSuite) TestTraceRedactsToken(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"accounts": []}`}
+	client := newStubClient(c, &tr)
+
+	buf := bytes.Buffer{}
+	client.SetTraceLogger(log.New(&buf, "", 0))
+
+	_, err := client.Accounts()
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].Header.Get("Authorization"), check.Equals, "Bearer secret-token")
+
+	c.Log(buf.String())
+	c.Assert(strings.Contains(buf.String(), "GET https://api-fxpractice.oanda.com/v1/accounts"),
+		check.Equals, true)
+	c.Assert(strings.Contains(buf.String(), "secret-token"), check.Equals, false)
+}
+
+func (s *ClientSuite) TestNewJSONRequest(c *check.C) {
+	client := newStubClient(c, &stubTransport{StatusCode: 200})
+
+	body := struct {
+		Units int `json:"units"`
+	}{10}
+	req, err := client.NewJSONRequest("POST", "/v1/accounts/1/orders", &body)
+	c.Assert(err, check.IsNil)
+	c.Assert(req.Header.Get("Content-Type"), check.Equals, "application/json")
+	data, err := ioutil.ReadAll(req.Body)
+	c.Assert(err, check.IsNil)
+	c.Assert(string(data), check.Equals, `{"units":10}`)
+
+	req, err = client.NewJSONRequest("GET", "/v1/accounts", nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(req.Header.Get("Content-Type"), check.Equals, "")
+}
+
+func (s *ClientSuite) TestValidationError(c *check.C) {
+	tr := stubTransport{StatusCode: 400, Body: `{"code": 1, "message": "Invalid units"}`}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err := client.NewTrade(oanda.Buy, 1, "eur.usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "instrument")
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	_, err = client.PollPrices()
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "instruments")
+
+	_, err = client.NewTrade(oanda.Buy, 0, "eur_usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.ApiError{})
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
+
+func (s *ClientSuite) TestPollAndDecode(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Header: http.Header{"Etag": {"abc"}}, Body: `{"value": 1}`},
+			{StatusCode: 304},
+			{StatusCode: 200, Body: ""},
+			{StatusCode: 200, Header: http.Header{"Etag": {"def"}}, Body: `{"value": 2}`},
+			{StatusCode: 400, Body: `{"code": 1, "message": "Invalid request"}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	pr, err := client.NewPollRequest("/v1/test")
+	c.Assert(err, check.IsNil)
+
+	v := struct {
+		Value int `json:"value"`
+	}{}
+	modified, err := pr.PollAndDecode(&v)
+	c.Assert(err, check.IsNil)
+	c.Assert(modified, check.Equals, true)
+	c.Assert(v.Value, check.Equals, 1)
+	c.Assert(tr.Requests[len(tr.Requests)-1].Header.Get("If-None-Match"), check.Equals, "abc")
+
+	for i := 0; i < 2; i++ {
+		modified, err = pr.PollAndDecode(&v)
+		c.Assert(err, check.IsNil)
+		c.Assert(modified, check.Equals, false)
+		c.Assert(v.Value, check.Equals, 1)
+	}
+
+	modified, err = pr.PollAndDecode(&v)
+	c.Assert(err, check.IsNil)
+	c.Assert(modified, check.Equals, true)
+	c.Assert(v.Value, check.Equals, 2)
+	c.Assert(tr.Requests[len(tr.Requests)-1].Header.Get("If-None-Match"), check.Equals, "def")
+
+	_, err = pr.PollAndDecode(&v)
+	c.Assert(err, check.FitsTypeOf, &oanda.ApiError{})
+	c.Assert(tr.Requests, check.HasLen, 5)
+}
+
+func (s *ClientSuite) TestDecodeResponseCode(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Body: `{"accounts": [{"accountId": 1}]}`},
+			{StatusCode: 200, Body: `{"code": 0, "message": "Informational", "accounts": []}`},
+			{StatusCode: 400, Body: `{"message": "No code"}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	accs, err := client.Accounts()
+	c.Assert(err, check.IsNil)
+	c.Assert(accs, check.HasLen, 1)
+	c.Assert(accs[0].AccountId, check.Equals, oanda.Id(1))
+
+	accs, err = client.Accounts()
+	c.Assert(err, check.IsNil)
+	c.Assert(accs, check.HasLen, 0)
+
+	_, err = client.Accounts()
+	c.Assert(err, check.FitsTypeOf, &oanda.ApiError{})
+	c.Assert(err.(*oanda.ApiError).Code, check.Equals, 0)
+	c.Assert(err.(*oanda.ApiError).Message, check.Equals, "No code")
+}
+
+func (s *ClientSuite) TestPollRequestString(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Header: http.Header{"Etag": {"abc"}}, Body: `{"prices": []}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	pp, err := client.NewPricePoller(time.Unix(1400000000, 0), "eur_usd")
+	c.Assert(err, check.IsNil)
+
+	pr := pp.PollRequest()
+	c.Assert(pr.URL().Query().Get("since"), check.Equals, "1400000000")
+	c.Assert(pr.String(), check.Equals, "GET https://api-fxpractice.oanda.com/v1/prices?"+
+		"instruments=EUR_USD&since=1400000000 [Authorization: Bearer [REDACTED]; "+
+		"X-Accept-Datetime-Format: UNIX]")
+
+	_, err = pp.Poll()
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(pr.String(), "If-None-Match: abc"), check.Equals, true)
+	c.Assert(strings.Contains(pr.String(), "secret-token"), check.Equals, false)
+}
+
+// blockingTransport is an http.RoundTripper that blocks until the context of a request is done.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-time.After(5 * time.Second):
+		return nil, errors.New("request was not cancelled")
+	}
+}
+
+func (s *ClientSuite) TestRequestTimeout(c *check.C) {
+	client, err := oanda.NewClient("fxpractice", "secret-token",
+		&http.Client{Transport: blockingTransport{}}, oanda.WithRequestTimeout(50*time.Millisecond))
+	c.Assert(err, check.IsNil)
+
+	start := time.Now()
+	_, err = client.PollPrices("eur_usd")
+	c.Assert(err, check.ErrorMatches, ".*deadline exceeded.*")
+	_, err = client.Accounts()
+	c.Assert(err, check.ErrorMatches, ".*deadline exceeded.*")
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+}
+
+func (s *ClientSuite) TestValidateToken(c *check.C) {
+	token := strings.Repeat("0123456789abcdef", 2) + "-" + strings.Repeat("fedcba9876543210", 2)
+	c.Assert(oanda.ValidateTokenFormat(token), check.IsNil)
+
+	for _, bad := range []string{"", "secret-token", token + "0", " " + token[1:],
+		strings.Replace(token, "-", "_", 1)} {
+
+		err := oanda.ValidateTokenFormat(bad)
+		c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{}, check.Commentf("%q", bad))
+		c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "token")
+	}
+
+	// Malformed tokens fail without contacting the servers.
+	tr := stubTransport{StatusCode: 200, Body: `{"accounts": []}`}
+	_, err := oanda.NewClient("fxpractice", token+"\n", &http.Client{Transport: &tr},
+		oanda.WithValidateToken())
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err, check.ErrorMatches, ".*whitespace at position 65.*")
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	// Without the option the token is passed on unchecked.
+	_, err = oanda.NewClient("fxpractice", "secret token", &http.Client{Transport: &tr})
+	c.Assert(err, check.IsNil)
+
+	client, err := oanda.NewClient("fxpractice", token, &http.Client{Transport: &tr},
+		oanda.WithValidateToken())
+	c.Assert(err, check.IsNil)
+	c.Assert(client, check.NotNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/accounts")
+
+	tr = stubTransport{StatusCode: 401, Body: `{"code": 4, "message": "The access token ` +
+		`provided does not allow this request to be made"}`}
+	_, err = oanda.NewClient("fxtrade", token, &http.Client{Transport: &tr},
+		oanda.WithValidateToken())
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err, check.ErrorMatches, ".*fxtrade.*")
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import "time"
+
+// A Clock provides the time to the stream servers, pollers and TimedReaders of a Client.  The
+// default Clock is the system clock; a simulated Clock lets tests drive handlers through time
+// without sleeping.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the current time once duration d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is the Clock that reports the time of the system.
+var SystemClock Clock = systemClock{}
+
+// newTimer is like clock.After but also returns a function that releases the timer once the
+// channel is no longer needed.  Only timers of the SystemClock are released early; other Clocks
+// are left to expire on their own.
+func newTimer(clock Clock, d time.Duration) (<-chan time.Time, func()) {
+	if _, ok := clock.(systemClock); ok {
+		t := time.NewTimer(d)
+		return t.C, func() { t.Stop() }
+	}
+	return clock.After(d), func() {}
+}
+
+// SetClock configures the Clock of the client.  Use nil to restore the SystemClock.
+func (c *Client) SetClock(clock Clock) {
+	c.clk = clock
+}
+
+// clock returns the Clock of the client.
+func (c *Client) clock() Clock {
+	if c.clk == nil {
+		return SystemClock
+	}
+	return c.clk
+}
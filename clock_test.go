@@ -0,0 +1,92 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+// simClock is a simulated oanda.Clock in which time advances only when it is waited on.
+type simClock struct {
+	mtx sync.Mutex
+	now time.Time
+}
+
+func (sc *simClock) Now() time.Time {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	return sc.now
+}
+
+func (sc *simClock) After(d time.Duration) <-chan time.Time {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	sc.now = sc.now.Add(d)
+	c := make(chan time.Time, 1)
+	c <- sc.now
+	return c
+}
+
+type ClockSuite struct{}
+
+var _ = check.Suite(&ClockSuite{})
+
+func (s *ClockSuite) TestCandleStreamerSimulatedTime(c *check.C) {
+	clock := &simClock{now: time.Date(2014, 5, 13, 16, 0, 2, 0, time.UTC)}
+
+	// The stub returns the two most recent complete M1 candles and the incomplete current one.
+	candleJSON := func(t time.Time, complete bool) string {
+		return fmt.Sprintf(`{"time": "%d", "closeMid": 1.1, "complete": %v}`,
+			t.UnixNano()/1000, complete)
+	}
+	tr := stubTransport{
+		StatusCode: 200,
+		BodyFunc: func(req *http.Request) string {
+			cur := clock.Now().Truncate(time.Minute)
+			return fmt.Sprintf(`{"instrument": "EUR_USD", "granularity": "M1", "candles": [%s, %s, %s]}`,
+				candleJSON(cur.Add(-2*time.Minute), true),
+				candleJSON(cur.Add(-time.Minute), true),
+				candleJSON(cur, false))
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SetClock(clock)
+
+	cs, err := client.NewCandleStreamer("eur_usd", oanda.M1)
+	c.Assert(err, check.IsNil)
+
+	start := time.Now()
+	var received []time.Time
+	err = cs.Run(func(instr string, candle oanda.MidpointCandle) {
+		received = append(received, candle.Time.Time())
+		if len(received) == 3 {
+			cs.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+	c.Assert(received, check.HasLen, 3)
+	for i, t := range received {
+		expected := time.Date(2014, 5, 13, 16, i, 0, 0, time.UTC)
+		c.Assert(t.Equal(expected), check.Equals, true, check.Commentf("candle %d: %v", i, t))
+	}
+}
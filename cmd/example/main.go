@@ -38,11 +38,11 @@ func main() {
 	fmt.Println(instruments)
 
 	// Buy one unit of EUR/USD with a trailing stop of 10 pips.
-	tradeInfo, err := client.NewTrade(oanda.Buy, 1, "eur_usd", oanda.TrailingStop(10.0))
+	orderInfo, err := client.NewTrade(oanda.Buy, 1, "eur_usd", oanda.TrailingStop(10.0))
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println(tradeInfo)
+	fmt.Println(orderInfo)
 
 	// Create and run a price server.
 	priceServer, err := client.NewPriceServer("eur_usd")
@@ -54,8 +54,14 @@ func main() {
 		priceServer.Stop()
 	})
 
-	// Close the previously opened trade.
-	tradeCloseInfo, err := client.CloseTrade(tradeInfo.TradeId)
+	// Close the previously opened trade.  No trade is opened if the order only reduced or closed
+	// existing trades, which are reported in TradeReduced and TradesClosed instead.
+	if orderInfo.TradeOpened == nil {
+		fmt.Println("No trade opened; reduced:", orderInfo.TradeReduced, "closed:",
+			orderInfo.TradesClosed)
+		return
+	}
+	tradeCloseInfo, err := client.CloseTrade(orderInfo.TradeOpened.TradeId)
 	if err != nil {
 		panic(err)
 	}
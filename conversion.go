@@ -0,0 +1,247 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConversionContext converts monetary amounts into the home currency of an account.  Exchange
+// rates and instrument information are retrieved on first use and cached until Refresh is
+// called.  A ConversionContext is safe for concurrent use.
+type ConversionContext struct {
+	c            *Client
+	homeCurrency string
+
+	mtx         sync.Mutex
+	rates       map[string]float64
+	instruments map[string]InstrumentInfo
+}
+
+// NewConversionContext returns a ConversionContext for the selected account.
+func (c *Client) NewConversionContext() (*ConversionContext, error) {
+	if c.accountId == 0 {
+		return nil, &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	acc, err := c.Account(c.accountId)
+	if err != nil {
+		return nil, err
+	}
+	return newConversionContext(c, acc.Currency), nil
+}
+
+// ConversionContext returns the ConversionContext for the selected account.  The context is
+// created on first use and shared by all helpers of the Client that convert amounts into the
+// home currency, until a different account is selected.
+func (c *Client) ConversionContext() (*ConversionContext, error) {
+	c.convMtx.Lock()
+	defer c.convMtx.Unlock()
+	if c.convCtx == nil {
+		cc, err := c.NewConversionContext()
+		if err != nil {
+			return nil, err
+		}
+		c.convCtx = cc
+	}
+	return c.convCtx, nil
+}
+
+func newConversionContext(c *Client, homeCurrency string) *ConversionContext {
+	return &ConversionContext{
+		c:            c,
+		homeCurrency: strings.ToUpper(homeCurrency),
+		rates:        make(map[string]float64),
+	}
+}
+
+// HomeCurrency returns the currency of the account.
+func (cc *ConversionContext) HomeCurrency() string { return cc.homeCurrency }
+
+// Refresh discards all cached exchange rates and instrument information.
+func (cc *ConversionContext) Refresh() {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	cc.rates = make(map[string]float64)
+	cc.instruments = nil
+}
+
+// SetRate sets the number of units of home currency per unit of currency.  The rate is used until
+// Refresh is called.
+func (cc *ConversionContext) SetRate(currency string, rate float64) {
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	cc.rates[strings.ToUpper(currency)] = rate
+}
+
+// Rate returns the number of units of home currency per unit of currency.  The rate is derived
+// from the mid price of the instrument that pairs currency with the home currency or, if no such
+// instrument exists, from the cross rate via USD.
+func (cc *ConversionContext) Rate(currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == cc.homeCurrency {
+		return 1.0, nil
+	}
+
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	if rate, ok := cc.rates[currency]; ok {
+		return rate, nil
+	}
+	rate, err := cc.pairRate(currency, cc.homeCurrency)
+	if err != nil {
+		return 0, err
+	}
+	cc.rates[currency] = rate
+	return rate, nil
+}
+
+// ToHome converts amount in currency into the home currency.
+func (cc *ConversionContext) ToHome(amount float64, currency string) (float64, error) {
+	rate, err := cc.Rate(currency)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Pip returns the size of a pip for instrument.
+func (cc *ConversionContext) Pip(instrument string) (float64, error) {
+	info, err := cc.instrumentInfo(instrument)
+	if err != nil {
+		return 0, err
+	}
+	return info.Pip, nil
+}
+
+// PipValue returns the value in home currency of a one pip price change of units of instrument.
+func (cc *ConversionContext) PipValue(instrument string, units int) (float64, error) {
+	pip, err := cc.Pip(instrument)
+	if err != nil {
+		return 0, err
+	}
+	_, quote := SplitInstrument(instrument)
+	return cc.ToHome(pip*float64(units), quote)
+}
+
+// Pl returns the profit or loss in home currency of a trade of units of instrument that was
+// opened at openPrice, valued at price.
+func (cc *ConversionContext) Pl(instrument string, side TradeSide, units int, openPrice,
+	price float64) (float64, error) {
+
+	pl := (price - openPrice) * float64(units)
+	if side == Sell {
+		pl = -pl
+	}
+	_, quote := SplitInstrument(instrument)
+	return cc.ToHome(pl, quote)
+}
+
+// Notional returns the value in home currency of units of instrument at price, which is quoted in
+// the quote currency of instrument.
+func (cc *ConversionContext) Notional(instrument string, units int, price float64) (float64, error) {
+	_, quote := SplitInstrument(instrument)
+	return cc.ToHome(float64(units)*price, quote)
+}
+
+// Exposure returns the value in home currency of units of the base currency of instrument.
+func (cc *ConversionContext) Exposure(instrument string, units int) (float64, error) {
+	base, _ := SplitInstrument(instrument)
+	return cc.ToHome(float64(units), base)
+}
+
+// SplitInstrument returns the base and quote currencies of an instrument such as "EUR_USD".
+func SplitInstrument(instrument string) (base, quote string) {
+	parts := strings.SplitN(instrumentKey(instrument), "_", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// pairRate returns the number of units of currency "to" per unit of currency "from".  Must be
+// called with cc.mtx locked.
+func (cc *ConversionContext) pairRate(from, to string) (float64, error) {
+	if err := cc.loadInstruments(); err != nil {
+		return 0, err
+	}
+	direct, inverse := from+"_"+to, to+"_"+from
+	if _, ok := cc.instruments[direct]; ok {
+		return cc.midPrice(direct, false)
+	}
+	if _, ok := cc.instruments[inverse]; ok {
+		return cc.midPrice(inverse, true)
+	}
+	if from != "USD" && to != "USD" {
+		r1, err := cc.pairRate(from, "USD")
+		if err != nil {
+			return 0, err
+		}
+		r2, err := cc.pairRate("USD", to)
+		if err != nil {
+			return 0, err
+		}
+		return r1 * r2, nil
+	}
+	return 0, fmt.Errorf("No instrument to convert %s into %s", from, to)
+}
+
+func (cc *ConversionContext) midPrice(instrument string, invert bool) (float64, error) {
+	prices, err := cc.c.PollPrices(instrument)
+	if err != nil {
+		return 0, err
+	}
+	tick, ok := prices[instrument]
+	if !ok {
+		return 0, fmt.Errorf("No price for instrument %s", instrument)
+	}
+	mid := (tick.Bid + tick.Ask) / 2
+	if invert {
+		return 1 / mid, nil
+	}
+	return mid, nil
+}
+
+func (cc *ConversionContext) instrumentInfo(instrument string) (InstrumentInfo, error) {
+	instrument = instrumentKey(instrument)
+
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+
+	if err := cc.loadInstruments(); err != nil {
+		return InstrumentInfo{}, err
+	}
+	info, ok := cc.instruments[instrument]
+	if !ok {
+		return InstrumentInfo{}, fmt.Errorf("Unknown instrument %s", instrument)
+	}
+	return info, nil
+}
+
+// loadInstruments retrieves the instruments that are available to the account.  Must be called
+// with cc.mtx locked.
+func (cc *ConversionContext) loadInstruments() error {
+	if cc.instruments != nil {
+		return nil
+	}
+	instruments, err := cc.c.Instruments(nil, []InstrumentField{PipField})
+	if err != nil {
+		return err
+	}
+	cc.instruments = instruments
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"math"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type ConversionSuite struct{}
+
+var _ = check.Suite(&ConversionSuite{})
+
+func newConversionContext(c *check.C, prices string) *oanda.ConversionContext {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1": `{"accountId": 1, "accountCurrency": "GBP"}`,
+			"/v1/instruments": `{"instruments": [
+				{"instrument": "EUR_USD", "pip": "0.0001"},
+				{"instrument": "GBP_USD", "pip": "0.0001"},
+				{"instrument": "USD_JPY", "pip": "0.01"}
+			]}`,
+			"/v1/prices": prices,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+	cc, err := client.NewConversionContext()
+	c.Assert(err, check.IsNil)
+	c.Assert(cc.HomeCurrency(), check.Equals, "GBP")
+	return cc
+}
+
+func (s *ConversionSuite) TestRate(c *check.C) {
+	cc := newConversionContext(c, `{"prices": [
+		{"instrument": "GBP_USD", "bid": 1.49, "ask": 1.51}
+	]}`)
+
+	rate, err := cc.Rate("GBP")
+	c.Assert(err, check.IsNil)
+	c.Assert(rate, check.Equals, 1.0)
+
+	rate, err = cc.Rate("usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(rate-1/1.5) < 1e-12, check.Equals, true)
+
+	amount, err := cc.ToHome(150, "USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(amount-100) < 1e-9, check.Equals, true)
+}
+
+func (s *ConversionSuite) TestHelpers(c *check.C) {
+	cc := newConversionContext(c, `{"prices": []}`)
+	cc.SetRate("USD", 0.5)
+	cc.SetRate("EUR", 0.75)
+
+	pipValue, err := cc.PipValue("eur_usd", 10000)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(pipValue-0.5) < 1e-9, check.Equals, true)
+
+	pl, err := cc.Pl("EUR_USD", oanda.Sell, 10000, 1.1010, 1.1000)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(pl-5) < 1e-9, check.Equals, true)
+
+	exposure, err := cc.Exposure("EUR_USD", 1000)
+	c.Assert(err, check.IsNil)
+	c.Assert(exposure, check.Equals, 750.0)
+
+	_, err = cc.Rate("CHF")
+	c.Assert(err, check.NotNil)
+}
+
+func (s *ConversionSuite) TestEventHomeValue(c *check.C) {
+	cc := newConversionContext(c, `{"prices": []}`)
+	cc.SetRate("USD", 0.5)
+	cc.SetRate("JPY", 0.005)
+
+	notional, err := cc.Notional("EUR_USD", 1000, 1.2)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(notional-600) < 1e-9, check.Equals, true)
+
+	evt, err := oanda.EventFromJSON([]byte(`{"id": 1, "type": "MARKET_ORDER_CREATE",
+		"instrument": "USD_JPY", "units": 100, "side": "buy", "price": 120.0, "pl": 3.0}`))
+	c.Assert(err, check.IsNil)
+	tce := evt.(*oanda.TradeCreateEvent)
+	value, err := tce.HomeValue(cc)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(value-60) < 1e-9, check.Equals, true)
+
+	evt, err = oanda.EventFromJSON([]byte(`{"id": 2, "type": "STOP_LOSS_FILLED",
+		"instrument": "EUR_USD", "units": 1000, "side": "buy", "price": 1.1, "pl": -2.0}`))
+	c.Assert(err, check.IsNil)
+	value, err = evt.(*oanda.TradeCloseEvent).HomeValue(cc)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(value-550) < 1e-9, check.Equals, true)
+}
@@ -102,7 +102,7 @@ func EventFromJSON(data []byte) (Event, error) {
 	rawEvent := struct {
 		*evtHeaderContent
 		*evtBody
-	}{}
+	}{&evtHeaderContent{}, &evtBody{}}
 
 	if err := json.Unmarshal(data, &rawEvent); err != nil {
 		return nil, err
@@ -165,6 +165,12 @@ func (t *TradeCreateEvent) TradeReduced() *evtTradeDetail {
 	return nil
 }
 
+// HomeValue returns the value in home currency of the units that were traded at Price.  Note
+// that Pl, Interest and AccountBalance are already in home currency.
+func (t *TradeCreateEvent) HomeValue(cc *ConversionContext) (float64, error) {
+	return cc.Notional(t.Instrument(), t.Units(), t.Price())
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // LIMIT_ORDER_CREATE, STOP_ORDER_CREATE, MARKET_IF_TOUCHED_CREATE
 
@@ -231,7 +237,32 @@ type OrderFilledEvent struct {
 	body *evtBody
 }
 
-func (t *OrderFilledEvent) OrderId() Id { return t.body.OrderId }
+func (t *OrderFilledEvent) OrderId() Id             { return t.body.OrderId }
+func (t *OrderFilledEvent) Instrument() string      { return t.body.Instrument }
+func (t *OrderFilledEvent) Side() string            { return t.body.Side }
+func (t *OrderFilledEvent) Units() int              { return t.body.Units }
+func (t *OrderFilledEvent) Price() float64          { return t.body.Price }
+func (t *OrderFilledEvent) Pl() float64             { return t.body.Pl }
+func (t *OrderFilledEvent) Interest() float64       { return t.body.Interest }
+func (t *OrderFilledEvent) AccountBalance() float64 { return t.body.AccountBalance }
+func (t *OrderFilledEvent) TradeOpened() *evtTradeDetail {
+	if t.body.TradeOpened != nil {
+		return &evtTradeDetail{t.body.TradeOpened}
+	}
+	return nil
+}
+func (t *OrderFilledEvent) TradeReduced() *evtTradeDetail {
+	if t.body.TradeReduced != nil {
+		return &evtTradeDetail{t.body.TradeReduced}
+	}
+	return nil
+}
+
+// HomeValue returns the value in home currency of the units that were filled at Price.  Note
+// that Pl, Interest and AccountBalance are already in home currency.
+func (t *OrderFilledEvent) HomeValue(cc *ConversionContext) (float64, error) {
+	return cc.Notional(t.Instrument(), t.Units(), t.Price())
+}
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // TRADE_UPDATE
@@ -270,6 +301,12 @@ func (t *TradeCloseEvent) Interest() float64       { return t.body.Interest }
 func (t *TradeCloseEvent) AccountBalance() float64 { return t.body.AccountBalance }
 func (t *TradeCloseEvent) TradeId() Id             { return t.body.TradeId }
 
+// HomeValue returns the value in home currency of the units that were closed at Price.  Note
+// that Pl, Interest and AccountBalance are already in home currency.
+func (t *TradeCloseEvent) HomeValue(cc *ConversionContext) (float64, error) {
+	return cc.Notional(t.Instrument(), t.Units(), t.Price())
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // MIGRATE_TRADE_OPEN
 
@@ -292,6 +329,11 @@ func (t *MigrateTradeOpenEvent) TradeOpened() *evtTradeDetail {
 	return &evtTradeDetail{t.body.TradeOpened}
 }
 
+// HomeValue returns the value in home currency of the units of the migrated trade at Price.
+func (t *MigrateTradeOpenEvent) HomeValue(cc *ConversionContext) (float64, error) {
+	return cc.Notional(t.Instrument(), t.Units(), t.Price())
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // SET_MARGIN_RATE
 
@@ -312,7 +354,8 @@ type TransferFundsEvent struct {
 	body *evtBody
 }
 
-func (t *TransferFundsEvent) Amount() float64 { return t.body.Amount }
+func (t *TransferFundsEvent) Amount() float64         { return t.body.Amount }
+func (t *TransferFundsEvent) AccountBalance() float64 { return t.body.AccountBalance }
 
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // DAILY_INTEREST
@@ -360,7 +403,7 @@ func (c Count) applyEventsArg(v url.Values) {
 }
 
 func (i Instrument) applyEventsArg(v url.Values) {
-	v.Set("instrument", string(i))
+	v.Set("instrument", instrumentKey(string(i)))
 }
 
 func (ids Ids) applyEventsArg(v url.Values) {
@@ -387,16 +430,17 @@ func (c *Client) PollEvents(args ...EventsArg) ([]Event, error) {
 
 	s := struct {
 		Events []struct {
-			*evtHeaderContent
-			*evtBody
+			evtHeaderContent
+			evtBody
 		} `json:"transactions"`
 	}{}
 	if err = getAndDecode(c, urlStr, &s); err != nil {
 		return nil, err
 	}
 	events := []Event{}
-	for _, rawEvent := range s.Events {
-		evt, err := asEvent(rawEvent.evtHeaderContent, rawEvent.evtBody)
+	for i := range s.Events {
+		rawEvent := &s.Events[i]
+		evt, err := asEvent(&rawEvent.evtHeaderContent, &rawEvent.evtBody)
 		if err != nil {
 			return nil, err
 		}
@@ -405,6 +449,41 @@ func (c *Client) PollEvents(args ...EventsArg) ([]Event, error) {
 	return events, nil
 }
 
+// maxEventsCount is the maximum number of events that Oanda returns for a single request.
+const maxEventsCount = 500
+
+// eventHistory returns all events that occurred at or after time start in chronological order.
+// The transaction history is retrieved in pages of maxEventsCount events.  Optional arguments
+// args are added to every request.
+func (c *Client) eventHistory(start time.Time, args ...EventsArg) ([]Event, error) {
+	var history []Event
+	pageArgs := append([]EventsArg{Count(maxEventsCount)}, args...)
+	for {
+		events, err := c.PollEvents(pageArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, evt := range events {
+			if evt.Time().Time().Before(start) {
+				return reverseEvents(history), nil
+			}
+			history = append(history, evt)
+		}
+		if len(events) < maxEventsCount {
+			return reverseEvents(history), nil
+		}
+		lastId := events[len(events)-1].TranId()
+		pageArgs = append([]EventsArg{Count(maxEventsCount), MaxId(lastId - 1)}, args...)
+	}
+}
+
+func reverseEvents(events []Event) []Event {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
 // PollEvent returns data for a single event.
 func (c *Client) PollEvent(tranId Id) (Event, error) {
 	evtData := struct {
@@ -460,7 +539,7 @@ func (c *Client) FullEventHistory() (*url.URL, error) {
 		return nil, err
 	}
 
-	rsp, err := c.Do(req)
+	rsp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -481,14 +560,37 @@ type EventServer struct {
 	// If HeartbeatFunc is not nil it is invoked once for every heartbeat message that the
 	// EventServer receives.
 	HeartbeatFunc HeartbeatHandlerFunc
-	chanMap       *eventChans
-	srv           *messageServer
+
+	// Backoff determines the delays between reconnection attempts after the stream connection
+	// failed.  DefaultBackoff is used if Backoff is nil.
+	Backoff BackoffFunc
+
+	// If ReconnectFunc is not nil it is invoked every time that the EventServer retries its
+	// stream connection.  See ReconnectFunc.
+	ReconnectFunc ReconnectFunc
+
+	// StallTimeout is the time after which the stream connection is considered stalled if
+	// neither an event nor a heartbeat arrives, in which case the EventServer reconnects.  The
+	// default of defaultEventStallTimeout is used if StallTimeout is not greater than zero.
+	StallTimeout time.Duration
+
+	// If DedupSize is greater than zero the EventServer remembers the transaction ids of the
+	// DedupSize most recent events and drops events that it receives again, e.g. when Oanda
+	// redelivers transactions after a reconnect.
+	DedupSize int
+
+	chanMap *eventChans
+	srv     *messageServer
+	recent  *recentIds
 }
 
 type (
 	EventHandlerFunc func(Id, Event)
 )
 
+// defaultEventStallTimeout is the default EventServer.StallTimeout.
+const defaultEventStallTimeout = 20 * time.Second
+
 // NewEventServer returns an server instance for receiving events for the specified accountId(s).
 // If no accountId is specified events for all accountIds are received.
 //
@@ -513,7 +615,7 @@ func (c *Client) NewEventServer(accountId ...Id) (*EventServer, error) {
 		handleHeartbeatsFn: es.handleHeartbeats,
 	}
 
-	if s, err := c.newMessageServer(req, streamSrv, time.Second*20); err != nil {
+	if s, err := c.newMessageServer(req, streamSrv, defaultEventStallTimeout); err != nil {
 		return nil, err
 	} else {
 		es.srv = s
@@ -529,9 +631,30 @@ func (c *Client) NewEventServer(accountId ...Id) (*EventServer, error) {
 // for further information.
 func (es *EventServer) ConnectAndHandle(handleFn EventHandlerFunc) (err error) {
 	es.initServer(handleFn)
+	es.srv.backoff = es.Backoff
+	es.srv.reconnectFn = es.ReconnectFunc
+	es.srv.stallTimeout = defaultEventStallTimeout
+	if es.StallTimeout > 0 {
+		es.srv.stallTimeout = es.StallTimeout
+	}
+	if es.DedupSize > 0 && (es.recent == nil || es.recent.Cap() != es.DedupSize) {
+		es.recent = newRecentIds(es.DedupSize)
+	}
 	return es.srv.ConnectAndDispatch()
 }
 
+// LastHeartbeat returns the time at which the EventServer last received a heartbeat or the zero
+// time if no heartbeat has been received.
+func (es *EventServer) LastHeartbeat() time.Time {
+	return es.srv.LastHeartbeat()
+}
+
+// LastMessage returns the time at which the EventServer last received an event or the zero time
+// if no event has been received.
+func (es *EventServer) LastMessage() time.Time {
+	return es.srv.LastMessage()
+}
+
 // Stop terminates the events server and causes ConnectAndHandle() to return.
 func (es *EventServer) Stop() {
 	es.srv.Stop()
@@ -567,6 +690,10 @@ func (es *EventServer) handleMessages(msgC <-chan StreamMessage) {
 			return
 		}
 
+		if es.recent != nil && !es.recent.Add(evt.TranId()) {
+			continue
+		}
+
 		evtC, ok := es.chanMap.Get(evt.AccountId())
 		if !ok {
 			// FIXME: log error "unexpected accountId"
@@ -597,7 +724,7 @@ type eventChans struct {
 func (ec *eventChans) AccountIds() Ids {
 	ec.mtx.RLock()
 	defer ec.mtx.RUnlock()
-	accIds := make(Ids, len(ec.m))
+	accIds := make(Ids, 0, len(ec.m))
 	for accId := range ec.m {
 		accIds = append(accIds, accId)
 	}
@@ -617,6 +744,39 @@ func (ec *eventChans) Get(accountId Id) (chan Event, bool) {
 	return ch, ok
 }
 
+// recentIds is a set of the most recently added ids of bounded size.  When the set is full the
+// id that was added first is evicted.
+type recentIds struct {
+	ids  []Id
+	next int
+	set  map[Id]bool
+}
+
+func newRecentIds(size int) *recentIds {
+	return &recentIds{
+		ids: make([]Id, 0, size),
+		set: make(map[Id]bool, size),
+	}
+}
+
+func (ri *recentIds) Cap() int { return cap(ri.ids) }
+
+// Add adds id to the set and returns false if the set already holds id.
+func (ri *recentIds) Add(id Id) bool {
+	if ri.set[id] {
+		return false
+	}
+	if len(ri.ids) < cap(ri.ids) {
+		ri.ids = append(ri.ids, id)
+	} else {
+		delete(ri.set, ri.ids[ri.next])
+		ri.ids[ri.next] = id
+		ri.next = (ri.next + 1) % len(ri.ids)
+	}
+	ri.set[id] = true
+	return true
+}
+
 func newEventChans(accountIds Ids) *eventChans {
 	m := make(map[Id]chan Event, len(accountIds))
 	for _, accId := range accountIds {
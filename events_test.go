@@ -15,6 +15,7 @@
 package oanda_test
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -126,3 +127,44 @@ func (ts *TestEventSuite) TestEventServer(c *check.C) {
 	ts.Client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 0.75, expiry)
 	wg.Wait()
 }
+
+type EventServerSuite struct{}
+
+var _ = check.Suite(&EventServerSuite{})
+
+func (s *EventServerSuite) TestDedup(c *check.C) {
+	fill := func(id int) string {
+		return fmt.Sprintf(`{"transaction": {"id": %d, "accountId": 1, "time": "1400000000000000",
+			"type": "ORDER_FILLED", "instrument": "EUR_USD", "units": 1, "side": "buy"}}`, id)
+	}
+	// The second connection redelivers the transaction of the first.
+	tr := stubTransport{
+		StatusCode: 200,
+		Script: []stubResponse{
+			{StatusCode: 200, Body: fill(1)},
+			{StatusCode: 200, Body: fill(1) + "\n" + fill(2)},
+		},
+		Body: fill(2),
+	}
+	client := newStubClient(c, &tr)
+
+	es, err := client.NewEventServer(1)
+	c.Assert(err, check.IsNil)
+	es.DedupSize = 10
+
+	mtx := sync.Mutex{}
+	var received []oanda.Id
+	err = es.ConnectAndHandle(func(accountId oanda.Id, evt oanda.Event) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		received = append(received, evt.TranId())
+		if evt.TranId() == 2 {
+			es.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	c.Assert(received, check.DeepEquals, []oanda.Id{1, 2})
+}
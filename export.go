@@ -0,0 +1,109 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ledgerColumns are the columns of the CSV that is written by ExportEventsCSV.
+var ledgerColumns = []string{
+	"tranId", "time", "type", "instrument", "units", "price", "pl", "interest", "amount",
+	"accountBalance",
+}
+
+// ExportEventsCSV writes the transactions of the selected account that occurred at or after start
+// and before end to w as CSV in chronological order.  A zero end exports all transactions since
+// start.  The first record is a header with the columns tranId, time, type, instrument, units,
+// price, pl, interest, amount and accountBalance.  Times are formatted as RFC 3339 in UTC.
+// Columns that do not apply to the type of a transaction are left blank.
+func (c *Client) ExportEventsCSV(w io.Writer, start, end time.Time) error {
+	if c.accountId == 0 {
+		return &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	if !end.IsZero() && end.Before(start) {
+		return &ValidationError{Field: "end", Reason: "End must not be before start."}
+	}
+	events, err := c.eventHistory(start)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err = cw.Write(ledgerColumns); err != nil {
+		return err
+	}
+	for _, evt := range events {
+		if !end.IsZero() && !evt.Time().Time().Before(end) {
+			break
+		}
+		if err = cw.Write(ledgerRecord(evt)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ledgerRecord returns the CSV record of evt.
+func ledgerRecord(evt Event) []string {
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	record := make([]string, len(ledgerColumns))
+	record[0] = strconv.FormatUint(uint64(evt.TranId()), 10)
+	record[1] = evt.Time().Time().UTC().Format(time.RFC3339)
+	record[2] = evt.Type()
+	if e, ok := evt.(interface {
+		Instrument() string
+	}); ok {
+		record[3] = e.Instrument()
+	}
+	if e, ok := evt.(interface {
+		Units() int
+	}); ok {
+		record[4] = strconv.Itoa(e.Units())
+	}
+	if e, ok := evt.(interface {
+		Price() float64
+	}); ok {
+		record[5] = formatFloat(e.Price())
+	}
+	if e, ok := evt.(interface {
+		Pl() float64
+	}); ok {
+		record[6] = formatFloat(e.Pl())
+	}
+	if e, ok := evt.(interface {
+		Interest() float64
+	}); ok {
+		record[7] = formatFloat(e.Interest())
+	}
+	if e, ok := evt.(interface {
+		Amount() float64
+	}); ok {
+		record[8] = formatFloat(e.Amount())
+	}
+	if e, ok := evt.(interface {
+		AccountBalance() float64
+	}); ok {
+		record[9] = formatFloat(e.AccountBalance())
+	}
+	return record
+}
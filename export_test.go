@@ -0,0 +1,63 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"bytes"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type ExportSuite struct{}
+
+var _ = check.Suite(&ExportSuite{})
+
+func (s *ExportSuite) TestExportEventsCSV(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"transactions": [
+			{"id": 5, "type": "DAILY_INTEREST", "time": "1400000400000000", "interest": 0.02},
+			{"id": 4, "type": "TRANSFER_FUNDS", "time": "1400000300000000", "amount": 100,
+			 "accountBalance": 1200.5},
+			{"id": 3, "type": "TRADE_CLOSE", "time": "1400000200000000", "instrument": "EUR_USD",
+			 "units": 10, "side": "buy", "price": 1.2, "pl": 1.5, "interest": 0.01,
+			 "accountBalance": 1100.5, "tradeId": 2},
+			{"id": 2, "type": "MARKET_ORDER_CREATE", "time": "1400000100000000",
+			 "instrument": "EUR_USD", "units": 10, "side": "buy", "price": 1.1, "pl": 0,
+			 "interest": 0, "accountBalance": 1099, "tradeOpened": {"id": 2, "units": 10}},
+			{"id": 1, "type": "CREATE", "time": "1400000000000000", "homeCurrency": "USD"}]}`,
+	}
+	client := newStubClient(c, &tr)
+
+	var buf bytes.Buffer
+	err := client.ExportEventsCSV(&buf, time.Time{}, time.Time{})
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	client.SelectAccount(1)
+	start := time.Unix(1400000100, 0)
+	err = client.ExportEventsCSV(&buf, start, start.Add(-time.Second))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	err = client.ExportEventsCSV(&buf, start, time.Unix(1400000400, 0))
+	c.Assert(err, check.IsNil)
+	c.Assert(buf.String(), check.Equals,
+		"tranId,time,type,instrument,units,price,pl,interest,amount,accountBalance\n"+
+			"2,2014-05-13T16:55:00Z,MARKET_ORDER_CREATE,EUR_USD,10,1.1,0,0,,1099\n"+
+			"3,2014-05-13T16:56:40Z,TRADE_CLOSE,EUR_USD,10,1.2,1.5,0.01,,1100.5\n"+
+			"4,2014-05-13T16:58:20Z,TRANSFER_FUNDS,,,,,,100,1200.5\n")
+}
@@ -0,0 +1,86 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+)
+
+// InstrumentClient is a Client that is scoped to a single instrument and candle granularity.  The
+// price and candle methods of an InstrumentClient apply to its instrument and granularity; all
+// other methods of the embedded Client are available unchanged.
+type InstrumentClient struct {
+	*Client
+	Instrument  string
+	Granularity Granularity
+}
+
+// ForInstrument returns an InstrumentClient for instrument and granularity that shares the
+// connection and selected account of the Client.
+func (c *Client) ForInstrument(instrument string, granularity Granularity) (*InstrumentClient, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
+	ic := InstrumentClient{
+		Client:      c,
+		Instrument:  instrument,
+		Granularity: granularity,
+	}
+	return &ic, nil
+}
+
+// PollPrice returns the current PriceTick of the instrument.
+func (ic *InstrumentClient) PollPrice() (PriceTick, error) {
+	prices, err := ic.Client.PollPrices(ic.Instrument)
+	if err != nil {
+		return PriceTick{}, err
+	}
+	tick, ok := prices[ic.Instrument]
+	if !ok {
+		return PriceTick{}, fmt.Errorf("No price for instrument %s", ic.Instrument)
+	}
+	return tick, nil
+}
+
+// PollMidpointCandles returns historical midpoint prices for the instrument and granularity.
+func (ic *InstrumentClient) PollMidpointCandles(args ...CandlesArg) (*MidpointCandles, error) {
+	return ic.Client.PollMidpointCandles(ic.Instrument, ic.Granularity, args...)
+}
+
+// PollBidAskCandles returns historical bid- and ask prices for the instrument and granularity.
+func (ic *InstrumentClient) PollBidAskCandles(args ...CandlesArg) (*BidAskCandles, error) {
+	return ic.Client.PollBidAskCandles(ic.Instrument, ic.Granularity, args...)
+}
+
+// NewPriceServer returns a PriceServer for the instrument.
+func (ic *InstrumentClient) NewPriceServer() (*PriceServer, error) {
+	return ic.Client.NewPriceServer(ic.Instrument)
+}
+
+// NewCandleStreamer returns a CandleStreamer for the instrument and granularity.
+func (ic *InstrumentClient) NewCandleStreamer() (*CandleStreamer, error) {
+	return ic.Client.NewCandleStreamer(ic.Instrument, ic.Granularity)
+}
+
+// NewTrade submits a MarketOrder request for the instrument.
+func (ic *InstrumentClient) NewTrade(side TradeSide, units int, args ...NewTradeArg) (*OrderResponse, error) {
+	return ic.Client.NewTrade(side, units, ic.Instrument, args...)
+}
+
+// Position returns the position in the instrument for the selected account.
+func (ic *InstrumentClient) Position() (*Position, error) {
+	return ic.Client.Position(ic.Instrument)
+}
@@ -0,0 +1,57 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type InstrumentClientSuite struct{}
+
+var _ = check.Suite(&InstrumentClientSuite{})
+
+func (s *InstrumentClientSuite) TestDefaults(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "bid": 1.1, "ask": 1.2}]}`,
+			"/v1/candles": `{"instrument": "EUR_USD", "granularity": "M5", "candles": [
+				{"time": "1400000000000000", "closeMid": 1.15, "complete": true}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.ForInstrument("eur.usd", oanda.M5)
+	c.Assert(err, check.NotNil)
+
+	ic, err := client.ForInstrument("eur/usd", oanda.M5)
+	c.Assert(err, check.IsNil)
+	c.Assert(ic.Instrument, check.Equals, "EUR_USD")
+
+	tick, err := ic.PollPrice()
+	c.Assert(err, check.IsNil)
+	c.Assert(tick.Bid, check.Equals, 1.1)
+	c.Assert(tr.Requests[0].URL.Query().Get("instruments"), check.Equals, "EUR_USD")
+
+	candles, err := ic.PollMidpointCandles(oanda.Count(1))
+	c.Assert(err, check.IsNil)
+	c.Assert(candles.Candles, check.HasLen, 1)
+	q := tr.Requests[1].URL.Query()
+	c.Assert(q.Get("instrument"), check.Equals, "EUR_USD")
+	c.Assert(q.Get("granularity"), check.Equals, "M5")
+	c.Assert(q.Get("count"), check.Equals, "1")
+}
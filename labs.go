@@ -24,16 +24,87 @@ import (
 	"time"
 )
 
+// Period is the length in seconds of the period for which Forex Labs data is requested.
 type Period int64
 
 const (
-	Hour  Period = Period(time.Hour)
+	Hour  Period = 3600
 	Day   Period = 24 * Hour
 	Week  Period = 7 * Day
 	Month Period = 2592000
 	Year  Period = 31536000
 )
 
+// Periods is a set of periods.
+type Periods []Period
+
+// Contains returns true if p is in the set.
+func (ps Periods) Contains(p Period) bool {
+	for _, v := range ps {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+// The periods that are supported by each of the Forex Labs endpoints.
+var (
+	CalendarPeriods       = Periods{Hour, 12 * Hour, Day, Week, Month, 3 * Month, 6 * Month, Year}
+	PositionRatiosPeriods = Periods{Day, 2 * Day, Week, Month, 3 * Month, 6 * Month, Year}
+	SpreadsPeriods        = Periods{Hour, 12 * Hour, Day, Week, Month, 3 * Month, 6 * Month, Year}
+	OrderBooksPeriods     = Periods{Hour, 6 * Hour, Day, 2 * Day, Week, Month, 3 * Month, 6 * Month, Year}
+	AutochartistPeriods   = Periods{4 * Hour, 8 * Hour, Day, 3 * Day, Week, Month}
+)
+
+func validatePeriod(endpoint string, period Period, supported Periods) error {
+	if !supported.Contains(period) {
+		return &ValidationError{
+			Field: "period",
+			Reason: fmt.Sprintf("Period %d is not supported by %s. Supported periods are %v.",
+				period, endpoint, supported),
+		}
+	}
+	return nil
+}
+
+// LabsUnavailableError is returned by the Forex Labs methods when the labs service responds with
+// something other than a JSON document, such as the HTML error page that is served while the
+// service is down.  The status package reports known outages of Oanda services.
+type LabsUnavailableError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *LabsUnavailableError) Error() string {
+	return fmt.Sprintf("Forex Labs service unavailable (status %d): %v", e.StatusCode, e.Err)
+}
+
+// getLabsAndDecode is like getAndDecode but reports a response that is not a JSON document as a
+// LabsUnavailableError.
+func getLabsAndDecode(c *Client, urlStr string, v interface{}) error {
+	req, err := c.NewRequest("GET", urlStr, nil)
+	if err != nil {
+		return err
+	}
+
+	debug("request %s %s %v\n", req.Method, req.URL, redactedHeader(req.Header))
+
+	req, cancel := c.withTimeout(req)
+	defer cancel()
+	rsp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer closeResponse(rsp.Body)
+
+	err = decodeResponse(rsp, v)
+	if _, ok := err.(*json.SyntaxError); ok {
+		return &LabsUnavailableError{StatusCode: rsp.StatusCode, Err: err}
+	}
+	return err
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Calendar
 
@@ -46,22 +117,122 @@ type CalendarEvent struct {
 	Previous  float64 `json:"previous,string"`
 	Actual    float64 `json:"actual,string"`
 	Market    float64 `json:"market,string"`
+	// Impact is the importance of the event as provided by Oanda, or UnknownImpact if Oanda did
+	// not classify the event.  See ImpactLevel.
+	Impact Impact `json:"impact"`
+}
+
+// Time returns the time of the event.
+func (ce CalendarEvent) Time() time.Time {
+	return time.Unix(ce.Timestamp, 0)
+}
+
+// ImpactLevel returns the importance of the event.  This is Impact if Oanda classified the event
+// and otherwise an estimate based on the title of the event: central bank decisions, employment,
+// inflation and GDP figures are considered high impact, and other well known indicators medium
+// impact.
+func (ce CalendarEvent) ImpactLevel() Impact {
+	if ce.Impact != UnknownImpact {
+		return ce.Impact
+	}
+	title := strings.ToLower(ce.Title)
+	for _, kw := range highImpactKeywords {
+		if strings.Contains(title, kw) {
+			return HighImpact
+		}
+	}
+	for _, kw := range mediumImpactKeywords {
+		if strings.Contains(title, kw) {
+			return MediumImpact
+		}
+	}
+	return LowImpact
 }
 
 func (ce CalendarEvent) String() string {
-	t := time.Unix(0, ce.Timestamp*1000)
 	return fmt.Sprintf("CalendarEvent{Title: %s, Timestamp: %s, Unit: %s, Currency: %s, "+
 		"Forecast: %v, Previous: %v, Actual: %v, Market: %v}", ce.Title,
-		t.Format(time.RFC3339), ce.Unit, ce.Currency, ce.Forecast, ce.Previous, ce.Actual,
+		ce.Time().UTC().Format(time.RFC3339), ce.Unit, ce.Currency, ce.Forecast, ce.Previous, ce.Actual,
 		ce.Market)
 }
 
+// Impact is the importance of a CalendarEvent for the markets.
+type Impact int
+
+const (
+	UnknownImpact Impact = iota
+	LowImpact
+	MediumImpact
+	HighImpact
+)
+
+func (i Impact) String() string {
+	switch i {
+	case LowImpact:
+		return "low"
+	case MediumImpact:
+		return "medium"
+	case HighImpact:
+		return "high"
+	}
+	return "unknown"
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.  Oanda reports the impact of an event
+// as a number from 1 (low) to 3 (high), which may be quoted.  Missing or unrecognised values
+// decode as UnknownImpact.
+func (i *Impact) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	switch strings.ToLower(s) {
+	case "1", "low":
+		*i = LowImpact
+	case "2", "medium":
+		*i = MediumImpact
+	case "3", "high":
+		*i = HighImpact
+	default:
+		*i = UnknownImpact
+	}
+	return nil
+}
+
+// Title keywords of calendar events that are estimated to be of high and medium impact.
+var (
+	highImpactKeywords = []string{
+		"interest rate", "rate decision", "cash rate", "fomc", "monetary policy", "nonfarm",
+		"non-farm", "employment change", "unemployment", "cpi", "consumer price", "gdp",
+	}
+	mediumImpactKeywords = []string{
+		"retail sales", "pmi", "trade balance", "ppi", "producer price", "industrial production",
+		"manufacturing", "housing starts", "building permits", "consumer confidence",
+		"sentiment", "jobless claims", "minutes", "speech", "speaks", "durable goods",
+	}
+)
+
+// FilterCalendarEvents returns the events whose ImpactLevel is at least minImpact.
+func FilterCalendarEvents(events []CalendarEvent, minImpact Impact) []CalendarEvent {
+	filtered := make([]CalendarEvent, 0, len(events))
+	for _, ce := range events {
+		if ce.ImpactLevel() >= minImpact {
+			filtered = append(filtered, ce)
+		}
+	}
+	return filtered
+}
+
 // Calendar returns and array of economic calendar events associated with an instrument. Events
 // can include economic indicator data or they can solely be be news about important meetings.
 //
 // See http://developer.oanda.com/docs/v1/forex-labs/#calendar for further information.
 func (c *Client) Calendar(instrument string, period Period) ([]CalendarEvent, error) {
-	instrument = strings.ToUpper(instrument)
+	if err := validatePeriod("Calendar", period, CalendarPeriods); err != nil {
+		return nil, err
+	}
+
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse("/labs/v1/calendar")
 	if err != nil {
 		return nil, err
@@ -72,7 +243,7 @@ func (c *Client) Calendar(instrument string, period Period) ([]CalendarEvent, er
 	u.RawQuery = q.Encode()
 
 	ces := make([]CalendarEvent, 0)
-	if err = getAndDecode(c, u.String(), &ces); err != nil {
+	if err = getLabsAndDecode(c, u.String(), &ces); err != nil {
 		return nil, err
 	}
 	return ces, nil
@@ -127,7 +298,14 @@ func (pr *PositionRatios) UnmarshalJSON(data []byte) error {
 // See http://developer.oanda.com/docs/v1/forex-labs/#historical-position-ratios for further
 // information.
 func (c *Client) PositionRatios(instrument string, period Period) (*PositionRatios, error) {
-	instrument = strings.ToUpper(instrument)
+	if err := validatePeriod("PositionRatios", period, PositionRatiosPeriods); err != nil {
+		return nil, err
+	}
+
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse("/labs/v1/historical_position_ratios")
 	if err != nil {
 		return nil, err
@@ -138,7 +316,7 @@ func (c *Client) PositionRatios(instrument string, period Period) (*PositionRati
 	u.RawQuery = q.Encode()
 
 	pr := PositionRatios{}
-	if err = getAndDecode(c, u.String(), &pr); err != nil {
+	if err = getLabsAndDecode(c, u.String(), &pr); err != nil {
 		return nil, err
 	}
 	return &pr, nil
@@ -181,7 +359,14 @@ func (s Spreads) String() string {
 //
 // See http://developer.oanda.com/docs/v1/forex-labs/#spreads for further information.
 func (c *Client) Spreads(instrument string, period Period, unique bool) (*Spreads, error) {
-	instrument = strings.ToUpper(instrument)
+	if err := validatePeriod("Spreads", period, SpreadsPeriods); err != nil {
+		return nil, err
+	}
+
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse("/labs/v1/spreads")
 	if err != nil {
 		return nil, err
@@ -197,7 +382,7 @@ func (c *Client) Spreads(instrument string, period Period, unique bool) (*Spread
 	u.RawQuery = q.Encode()
 
 	s := Spreads{}
-	if err = getAndDecode(c, u.String(), &s); err != nil {
+	if err = getLabsAndDecode(c, u.String(), &s); err != nil {
 		return nil, err
 	}
 	return &s, nil
@@ -228,7 +413,10 @@ func (c CommitmentsOfTraders) String() string {
 // The commitments of traders report is released by the CFTC and provides a breakdown of each
 // Tuesday's open interest.
 func (c *Client) CommitmentsOfTraders(instrument string) ([]CommitmentsOfTraders, error) {
-	instrument = strings.ToUpper(instrument)
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse("/labs/v1/commitments_of_traders")
 	if err != nil {
 		return nil, err
@@ -238,7 +426,7 @@ func (c *Client) CommitmentsOfTraders(instrument string) ([]CommitmentsOfTraders
 	u.RawQuery = q.Encode()
 
 	m := make(map[string][]CommitmentsOfTraders)
-	if err = requestAndDecode(c, "GET", u.String(), nil, &m); err != nil {
+	if err = getLabsAndDecode(c, u.String(), &m); err != nil {
 		return nil, err
 	}
 
@@ -321,7 +509,14 @@ func (obs *OrderBooks) UnmarshalJSON(data []byte) error {
 //
 // See http://developer.oanda.com/docs/v1/forex-labs/#orderbook for further information.
 func (c *Client) OrderBooks(instrument string, period Period) (OrderBooks, error) {
-	instrument = strings.ToUpper(instrument)
+	if err := validatePeriod("OrderBooks", period, OrderBooksPeriods); err != nil {
+		return nil, err
+	}
+
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 
 	u, err := url.Parse("/labs/v1/orderbook_data")
 	if err != nil {
@@ -333,7 +528,7 @@ func (c *Client) OrderBooks(instrument string, period Period) (OrderBooks, error
 	u.RawQuery = q.Encode()
 
 	obs := make(OrderBooks, 0)
-	if err = getAndDecode(c, u.String(), &obs); err != nil {
+	if err = getLabsAndDecode(c, u.String(), &obs); err != nil {
 		return nil, err
 	}
 	obs.Sort()
@@ -388,7 +583,7 @@ type AutochartistArg interface {
 }
 
 func (i Instrument) applyAutochartistArg(v url.Values) {
-	v.Set("instrument", strings.ToUpper(string(i)))
+	v.Set("instrument", instrumentKey(string(i)))
 }
 
 func (p Period) applyAutochartistArg(v url.Values) {
@@ -528,6 +723,13 @@ func (p AutochartistPattern) String() string {
 
 // AutochartistPattern
 func (c *Client) AutochartistPattern(arg ...AutochartistArg) (*AutochartistPattern, error) {
+	for _, a := range arg {
+		if period, ok := a.(Period); ok {
+			if err := validatePeriod("AutochartistPattern", period, AutochartistPeriods); err != nil {
+				return nil, err
+			}
+		}
+	}
 	u, err := url.Parse("/labs/v1/signal/autochartist")
 	if err != nil {
 		return nil, err
@@ -540,7 +742,7 @@ func (c *Client) AutochartistPattern(arg ...AutochartistArg) (*AutochartistPatte
 	u.RawQuery = q.Encode()
 
 	pattern := AutochartistPattern{}
-	if err := getAndDecode(c, u.String(), &pattern); err != nil {
+	if err := getLabsAndDecode(c, u.String(), &pattern); err != nil {
 		return nil, err
 	}
 	return &pattern, nil
@@ -32,6 +32,103 @@ func (ts *TestLabsSuite) SetUpSuite(c *check.C) {
 	ts.SetUpAccount(c)
 }
 
+type LabsSuite struct{}
+
+var _ = check.Suite(&LabsSuite{})
+
+func (s *LabsSuite) TestUnsupportedPeriod(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{}`}
+	client := newStubClient(c, &tr)
+
+	_, err := client.Spreads("eur_usd", oanda.Period(12345), true)
+	c.Assert(err, check.NotNil)
+	c.Log(err)
+
+	_, err = client.Calendar("eur_usd", 2*oanda.Day)
+	c.Assert(err, check.NotNil)
+
+	_, err = client.AutochartistPattern(oanda.Year)
+	c.Assert(err, check.NotNil)
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	_, err = client.Spreads("eur_usd", oanda.Day, true)
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].URL.Query().Get("period"), check.Equals, "86400")
+}
+
+func (s *LabsSuite) TestLabsUnavailable(c *check.C) {
+	tr := stubTransport{StatusCode: 503, Body: `<html><body>Service Unavailable</body></html>`}
+	client := newStubClient(c, &tr)
+
+	_, err := client.Calendar("eur_usd", oanda.Day)
+	c.Assert(err, check.FitsTypeOf, &oanda.LabsUnavailableError{})
+	c.Assert(err.(*oanda.LabsUnavailableError).StatusCode, check.Equals, 503)
+
+	tr = stubTransport{StatusCode: 200, Body: `<html><body>Maintenance</body></html>`}
+	client = newStubClient(c, &tr)
+	_, err = client.CommitmentsOfTraders("eur_usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.LabsUnavailableError{})
+
+	tr = stubTransport{StatusCode: 400, Body: `{"code": 1, "message": "Invalid instrument"}`}
+	client = newStubClient(c, &tr)
+	_, err = client.Calendar("eur_usd", oanda.Day)
+	c.Assert(err, check.FitsTypeOf, &oanda.ApiError{})
+}
+
+func (s *LabsSuite) TestCalendarDecode(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `[
+		{"title": "GDP", "timestamp": 1400000000, "unit": "%", "currency": "EUR",
+		 "forecast": "0.2", "previous": "0.1", "actual": "0.3", "market": "0.2"},
+		{"title": "ECB Meeting", "timestamp": 1400086400, "currency": "EUR"}]`}
+	client := newStubClient(c, &tr)
+
+	events, err := client.Calendar("eur_usd", oanda.Day)
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 2)
+	c.Assert(events[0].Title, check.Equals, "GDP")
+	c.Assert(events[0].Actual, check.Equals, 0.3)
+	c.Assert(events[0].Time().Unix(), check.Equals, int64(1400000000))
+	c.Assert(events[0].String(), check.Matches, ".*Timestamp: 2014-05-13T16:53:20Z,.*")
+	c.Assert(events[1].Title, check.Equals, "ECB Meeting")
+
+	tr = stubTransport{StatusCode: 200, Body: `[]`}
+	client = newStubClient(c, &tr)
+	events, err = client.Calendar("eur_usd", oanda.Day)
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 0)
+}
+
+func (s *LabsSuite) TestCalendarImpact(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `[
+		{"title": "Trade Balance", "timestamp": 1400000000, "currency": "EUR", "impact": "3"},
+		{"title": "Retail Sales", "timestamp": 1400000000, "currency": "EUR", "impact": 1},
+		{"title": "ECB Interest Rate Decision", "timestamp": 1400000000, "currency": "EUR"},
+		{"title": "German PMI", "timestamp": 1400000000, "currency": "EUR"},
+		{"title": "Bank Holiday", "timestamp": 1400000000, "currency": "EUR"}]`}
+	client := newStubClient(c, &tr)
+
+	events, err := client.Calendar("eur_usd", oanda.Day)
+	c.Assert(err, check.IsNil)
+	c.Assert(events, check.HasLen, 5)
+	c.Assert(events[0].Impact, check.Equals, oanda.HighImpact)
+	c.Assert(events[1].Impact, check.Equals, oanda.LowImpact)
+	c.Assert(events[2].Impact, check.Equals, oanda.UnknownImpact)
+
+	var levels []oanda.Impact
+	for _, ce := range events {
+		levels = append(levels, ce.ImpactLevel())
+	}
+	c.Assert(levels, check.DeepEquals, []oanda.Impact{oanda.HighImpact, oanda.LowImpact,
+		oanda.HighImpact, oanda.MediumImpact, oanda.LowImpact})
+
+	high := oanda.FilterCalendarEvents(events, oanda.HighImpact)
+	c.Assert(high, check.HasLen, 2)
+	c.Assert(high[0].Title, check.Equals, "Trade Balance")
+	c.Assert(high[1].Title, check.Equals, "ECB Interest Rate Decision")
+	c.Assert(oanda.FilterCalendarEvents(events, oanda.MediumImpact), check.HasLen, 3)
+}
+
 func (ts *TestLabsSuite) TestLabsCalendar(c *check.C) {
 	events, err := ts.Client.Calendar("eur_usd", oanda.Year)
 	c.Assert(err, check.IsNil)
@@ -0,0 +1,95 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+	"math"
+)
+
+// MoneyScale is the number of Money units in one unit of currency.  Oanda reports monetary
+// amounts with at most four decimals.
+const MoneyScale = 10000
+
+// Money is a fixed-point monetary amount expressed in 1/MoneyScale units of currency.  Summing
+// Money values does not accumulate the rounding errors that occur when summing float64 values.
+type Money int64
+
+// NewMoney returns the Money value nearest to amount f.
+func NewMoney(f float64) Money {
+	if f < 0 {
+		return -Money(math.Floor(-f*MoneyScale + 0.5))
+	}
+	return Money(math.Floor(f*MoneyScale + 0.5))
+}
+
+// Float64 returns the amount as a float64.
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// String implements the fmt.Stringer interface.
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	return fmt.Sprintf("%s%d.%04d", sign, m/MoneyScale, m%MoneyScale)
+}
+
+// EventTotals holds the sums of the monetary fields of a set of events.
+type EventTotals struct {
+	// Pl is the realized profit or loss.
+	Pl Money
+	// Interest is the interest paid or received.
+	Interest Money
+	// Amount is the sum of funds transfers and fees.
+	Amount Money
+}
+
+// String implements the fmt.Stringer interface.
+func (et EventTotals) String() string {
+	return fmt.Sprintf("EventTotals{Pl: %v, Interest: %v, Amount: %v}", et.Pl, et.Interest,
+		et.Amount)
+}
+
+// Total returns the sum of Pl, Interest and Amount.
+func (et EventTotals) Total() Money {
+	return et.Pl + et.Interest + et.Amount
+}
+
+// SumEvents returns the totals of the monetary fields of events.  Each amount is converted to
+// Money before it is added so that the totals are exact to the precision reported by Oanda.
+func SumEvents(events []Event) EventTotals {
+	et := EventTotals{}
+	for _, evt := range events {
+		if e, ok := evt.(interface {
+			Pl() float64
+		}); ok {
+			et.Pl += NewMoney(e.Pl())
+		}
+		if e, ok := evt.(interface {
+			Interest() float64
+		}); ok {
+			et.Interest += NewMoney(e.Interest())
+		}
+		if e, ok := evt.(interface {
+			Amount() float64
+		}); ok {
+			et.Amount += NewMoney(e.Amount())
+		}
+	}
+	return et
+}
@@ -0,0 +1,59 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type MoneySuite struct{}
+
+var _ = check.Suite(&MoneySuite{})
+
+func (s *MoneySuite) TestNewMoney(c *check.C) {
+	c.Assert(oanda.NewMoney(1.2345), check.Equals, oanda.Money(12345))
+	c.Assert(oanda.NewMoney(-1.2345), check.Equals, oanda.Money(-12345))
+	c.Assert(oanda.NewMoney(0.00005), check.Equals, oanda.Money(1))
+	c.Assert(oanda.NewMoney(0.1).Float64(), check.Equals, 0.1)
+}
+
+func (s *MoneySuite) TestMoneyString(c *check.C) {
+	c.Assert(oanda.Money(12345).String(), check.Equals, "1.2345")
+	c.Assert(oanda.Money(-500).String(), check.Equals, "-0.0500")
+	c.Assert(oanda.Money(0).String(), check.Equals, "0.0000")
+}
+
+func (s *MoneySuite) TestSumEvents(c *check.C) {
+	var events []oanda.Event
+	for _, data := range []string{
+		`{"id": 1, "type": "TRADE_CLOSE", "pl": 0.1, "interest": 0.0001}`,
+		`{"id": 2, "type": "TAKE_PROFIT_FILLED", "pl": 0.2, "interest": 0.0002}`,
+		`{"id": 3, "type": "DAILY_INTEREST", "interest": -0.0003}`,
+		`{"id": 4, "type": "TRANSFER_FUNDS", "amount": 100}`,
+		`{"id": 5, "type": "FEE", "amount": -0.7}`,
+	} {
+		evt, err := oanda.EventFromJSON([]byte(data))
+		c.Assert(err, check.IsNil)
+		events = append(events, evt)
+	}
+
+	totals := oanda.SumEvents(events)
+	c.Assert(totals.Pl, check.Equals, oanda.NewMoney(0.3))
+	c.Assert(totals.Interest, check.Equals, oanda.Money(0))
+	c.Assert(totals.Amount, check.Equals, oanda.NewMoney(99.3))
+	c.Assert(totals.Total().String(), check.Equals, "99.6000")
+}
@@ -15,6 +15,7 @@
 package oanda
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -59,6 +60,23 @@ func (o Order) String() string {
 		o.Units, o.Instrument)
 }
 
+// IsExpired returns true if the order's expiry time has passed.
+func (o Order) IsExpired() bool {
+	return !o.Expiry.IsZero() && !o.Expiry.Time().After(time.Now())
+}
+
+// TimeToExpiry returns the duration until the order expires or 0 if the order has expired or has
+// no expiry time.
+func (o Order) TimeToExpiry() time.Duration {
+	if o.Expiry.IsZero() {
+		return 0
+	}
+	if d := o.Expiry.Time().Sub(time.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
 // LowerBound is an optional argument for Client methods NewOrder(), ModifyOrder() and
 // NewTrade().
 type LowerBound float64
@@ -105,49 +123,198 @@ func (ts TrailingStop) applyNewOrderArg(v url.Values) {
 	optionalArgs(v).SetFloat("trailingStop", float64(ts))
 }
 
+// TradeDetail describes an existing trade that was closed or reduced as the result of an order.
+type TradeDetail struct {
+	TradeId  Id      `json:"id"`
+	Units    int     `json:"units"`
+	Side     string  `json:"side"`
+	Pl       float64 `json:"pl"`
+	Interest float64 `json:"interest"`
+}
+
+// String implements the fmt.Stringer interface.
+func (td TradeDetail) String() string {
+	return fmt.Sprintf("TradeDetail{TradeId: %d, Side: %s, Units: %d, Pl: %v, Interest: %v}",
+		td.TradeId, td.Side, td.Units, td.Pl, td.Interest)
+}
+
+// OrderResponse is the result of the submission of an order with NewOrder or NewTrade.  Only the
+// fields that apply to the outcome of the order are set.  OrderOpened is set if a pending order
+// was created, TradeOpened if a new trade was opened, TradeReduced if an existing trade was
+// partially closed and TradesClosed holds the existing trades that were closed in full.
+//
+// For market orders RequestedUnits holds the units of the order, which FilledUnits and FillStatus
+// compare with the units that were actually executed.
+type OrderResponse struct {
+	RequestedUnits int           `json:"-"`
+	Instrument     string        `json:"instrument"`
+	Time           Time          `json:"time"`
+	Price          float64       `json:"price"`
+	OrderOpened    *Order        `json:"orderOpened"`
+	TradeOpened    *Trade        `json:"tradeOpened"`
+	TradeReduced   *TradeDetail  `json:"tradeReduced"`
+	TradesClosed   []TradeDetail `json:"tradesClosed"`
+}
+
+// String implements the fmt.Stringer interface.
+func (r OrderResponse) String() string {
+	return fmt.Sprintf("OrderResponse{Instrument: %s, Time: %v, Price: %v, OrderOpened: %v, "+
+		"TradeOpened: %v, TradeReduced: %v, TradesClosed: %v}", r.Instrument, r.Time, r.Price,
+		r.OrderOpened, r.TradeOpened, r.TradeReduced, r.TradesClosed)
+}
+
+// FillStatus describes to what extent a market order was executed.
+type FillStatus int
+
+const (
+	// Unfilled indicates that no units of the order were executed, or that the order was not a
+	// market order.
+	Unfilled FillStatus = iota
+	// PartiallyFilled indicates that fewer units than requested were executed.
+	PartiallyFilled
+	// Filled indicates that all requested units were executed.
+	Filled
+)
+
+func (fs FillStatus) String() string {
+	switch fs {
+	case PartiallyFilled:
+		return "partially filled"
+	case Filled:
+		return "filled"
+	}
+	return "unfilled"
+}
+
+// FilledUnits returns the number of units that were executed; i.e. the units of the trade that
+// was opened plus ReducedUnits.
+func (r OrderResponse) FilledUnits() int {
+	units := r.ReducedUnits()
+	if r.TradeOpened != nil {
+		units += r.TradeOpened.Units
+	}
+	return units
+}
+
+// ReducedUnits returns the number of units that were executed against existing trades on the
+// opposite side, either by reducing or by closing them.
+func (r OrderResponse) ReducedUnits() int {
+	units := 0
+	if r.TradeReduced != nil {
+		units += r.TradeReduced.Units
+	}
+	for _, td := range r.TradesClosed {
+		units += td.Units
+	}
+	return units
+}
+
+// FillStatus compares FilledUnits with RequestedUnits.
+func (r OrderResponse) FillStatus() FillStatus {
+	filled := r.FilledUnits()
+	switch {
+	case filled == 0 || r.RequestedUnits == 0:
+		return Unfilled
+	case filled < r.RequestedUnits:
+		return PartiallyFilled
+	}
+	return Filled
+}
+
+// submitOrder posts an order request and completes the orders and trades in the response with
+// the details that Oanda does not repeat.
+func (c *Client) submitOrder(data url.Values) (*OrderResponse, error) {
+	instrument := data.Get("instrument")
+	rsp := OrderResponse{}
+	urlStr := fmt.Sprintf("/v1/accounts/%d/orders", c.accountId)
+	if err := requestAndDecode(c, "POST", urlStr, data, &rsp); err != nil {
+		c.observeApiError(instrument, err)
+		return nil, err
+	}
+	if rsp.Instrument == "" {
+		rsp.Instrument = instrument
+	}
+	if data.Get("type") == "market" {
+		rsp.RequestedUnits, _ = strconv.Atoi(data.Get("units"))
+	}
+
+	// Oanda returns empty objects for the outcomes that do not apply.
+	if rsp.OrderOpened != nil && rsp.OrderOpened.OrderId == 0 {
+		rsp.OrderOpened = nil
+	}
+	if rsp.TradeOpened != nil && rsp.TradeOpened.TradeId == 0 {
+		rsp.TradeOpened = nil
+	}
+	if rsp.TradeReduced != nil && rsp.TradeReduced.TradeId == 0 {
+		rsp.TradeReduced = nil
+	}
+	if o := rsp.OrderOpened; o != nil {
+		o.Instrument = rsp.Instrument
+		o.Time = rsp.Time
+		o.Price = rsp.Price
+		o.OrderType = data.Get("type")
+		if o.Expiry.IsZero() {
+			o.Expiry = Time(data.Get("expiry"))
+		}
+	}
+	if t := rsp.TradeOpened; t != nil {
+		t.Instrument = rsp.Instrument
+		t.Time = rsp.Time
+		t.Price = rsp.Price
+	}
+	return &rsp, nil
+}
+
 // NewOrder creates and submits a new order.
 func (c *Client) NewOrder(orderType OrderType, side TradeSide, units int, instrument string,
-	price float64, expiry time.Time, args ...NewOrderArg) (*Order, error) {
-
-	instrument = strings.ToUpper(instrument)
-	expiryStr := strconv.Itoa(int(expiry.UTC().Unix()))
+	price float64, expiry time.Time, args ...NewOrderArg) (*OrderResponse, error) {
 
-	o := Order{
-		Side:       string(side),
-		Units:      units,
-		Instrument: instrument,
-		Price:      price,
-		OrderType:  string(orderType),
-		Expiry:     Time(expiryStr),
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
 	}
 	data := url.Values{
-		"type":       {o.OrderType},
-		"side":       {o.Side},
+		"type":       {string(orderType)},
+		"side":       {string(side)},
 		"units":      {strconv.Itoa(units)},
 		"instrument": {instrument},
-		"price":      {strconv.FormatFloat(price, 'f', -1, 64)},
-		"expiry":     {expiryStr},
+		"price":      {formatDecimal(price)},
+		"expiry":     {strconv.Itoa(int(expiry.UTC().Unix()))},
 	}
 	for _, arg := range args {
 		arg.applyNewOrderArg(data)
 	}
+	if err = validateBounds(data, price); err != nil {
+		return nil, err
+	}
+	return c.submitOrder(data)
+}
 
-	rspData := struct {
-		Instrument  string  `json:"instrument"`
-		Time        Time    `json:"time"`
-		Price       float64 `json:"price"`
-		OrderOpened *Order  `json:"orderOpened"`
-	}{
-		OrderOpened: &o,
+// validateBounds returns a ValidationError if the lowerBound and upperBound in the order request
+// data are inverted or do not straddle price.  If price is zero only inverted bounds are
+// reported.
+func validateBounds(data url.Values, price float64) error {
+	bound := func(k string) (float64, bool) {
+		f, err := strconv.ParseFloat(data.Get(k), 64)
+		return f, err == nil
 	}
-	urlStr := fmt.Sprintf("/v1/accounts/%d/orders", c.accountId)
-	if err := requestAndDecode(c, "POST", urlStr, data, &rspData); err != nil {
-		return nil, err
+	lb, hasLower := bound("lowerBound")
+	ub, hasUpper := bound("upperBound")
+
+	var reason string
+	switch {
+	case hasLower && hasUpper && lb > ub:
+		reason = fmt.Sprintf("Lower bound %v exceeds upper bound %v.", lb, ub)
+	case price == 0:
+		return nil
+	case hasLower && lb > price:
+		reason = fmt.Sprintf("Lower bound %v exceeds the price %v.", lb, price)
+	case hasUpper && ub < price:
+		reason = fmt.Sprintf("Upper bound %v is below the price %v.", ub, price)
+	default:
+		return nil
 	}
-	o.Instrument = rspData.Instrument
-	o.Time = rspData.Time
-	o.Price = rspData.Price
-	return &o, nil
+	return &ValidationError{Field: "bounds", Reason: reason}
 }
 
 // Order returns information about an existing order.
@@ -185,7 +352,7 @@ func (cnt Count) applyOrdersArg(v url.Values) {
 }
 
 func (in Instrument) applyOrdersArg(v url.Values) {
-	v.Set("instrument", string(in))
+	v.Set("instrument", instrumentKey(string(in)))
 }
 
 // Orders returns an array with all orders that match the optional arguments (if any). Supported
@@ -202,13 +369,102 @@ func (c *Client) Orders(args ...OrdersArg) ([]Order, error) {
 	}
 	u.RawQuery = q.Encode()
 
-	rsp := struct {
-		Orders []Order `json:"orders"`
-	}{}
-	if err := getAndDecode(c, u.String(), &rsp); err != nil {
+	var raw json.RawMessage
+	if err := getAndDecode(c, u.String(), &raw); err != nil {
+		return nil, err
+	}
+	var orders []Order
+	if err := decodeList(raw, "orders", &orders); err != nil {
 		return nil, err
 	}
-	return rsp.Orders, nil
+	return orders, nil
+}
+
+// OrderStatus is the final disposition of an order in the order history.
+type OrderStatus string
+
+const (
+	OrderOpen      OrderStatus = "open"
+	OrderFilled    OrderStatus = "filled"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// OrderLifecycle represents an order as reconstructed from the transaction history.  The
+// embedded Order holds the order as it was created.
+type OrderLifecycle struct {
+	Order
+	Status OrderStatus
+	// CloseTime is the time at which the order was filled or cancelled.
+	CloseTime Time
+	// FillPrice is the price at which the order was filled.
+	FillPrice float64
+	// Reason is the reason why the order was cancelled.
+	Reason string
+}
+
+// String implements the fmt.Stringer interface.
+func (ol OrderLifecycle) String() string {
+	return fmt.Sprintf("OrderLifecycle{OrderId: %d, Side: %s, Units: %d, Instrument: %s, "+
+		"Status: %s}", ol.OrderId, ol.Side, ol.Units, ol.Instrument, ol.Status)
+}
+
+// OrderHistory returns the lifecycles of all orders that were created between start and end,
+// inclusive, in the order in which they were created.  Orders that were filled or cancelled
+// after end are reported with their final status.
+func (c *Client) OrderHistory(start, end time.Time) ([]OrderLifecycle, error) {
+	events, err := c.eventHistory(start)
+	if err != nil {
+		return nil, err
+	}
+
+	orderTypes := map[string]OrderType{
+		"LIMIT_ORDER_CREATE":             Limit,
+		"STOP_ORDER_CREATE":              Stop,
+		"MARKET_IF_TOUCHED_ORDER_CREATE": MarketIfTouched,
+	}
+
+	history := []OrderLifecycle{}
+	idx := make(map[Id]int)
+	for _, evt := range events {
+		switch e := evt.(type) {
+		case *OrderCreateEvent:
+			if e.Time().Time().After(end) {
+				continue
+			}
+			idx[e.TranId()] = len(history)
+			history = append(history, OrderLifecycle{
+				Order: Order{
+					OrderId:      e.TranId(),
+					Units:        e.Units(),
+					Instrument:   e.Instrument(),
+					Side:         e.Side(),
+					Price:        e.Price(),
+					Time:         e.Time(),
+					StopLoss:     e.StopLossPrice(),
+					TakeProfit:   e.TakeProfitPrice(),
+					TrailingStop: e.TrailingStopLossDistance(),
+					OrderType:    string(orderTypes[e.Type()]),
+					Expiry:       e.Expiry(),
+					UpperBound:   e.UpperBound(),
+					LowerBound:   e.LowerBound(),
+				},
+				Status: OrderOpen,
+			})
+		case *OrderFilledEvent:
+			if i, ok := idx[e.OrderId()]; ok {
+				history[i].Status = OrderFilled
+				history[i].CloseTime = e.Time()
+				history[i].FillPrice = e.Price()
+			}
+		case *OrderCancelEvent:
+			if i, ok := idx[e.OrderId()]; ok {
+				history[i].Status = OrderCancelled
+				history[i].CloseTime = e.Time()
+				history[i].Reason = e.Reason()
+			}
+		}
+	}
+	return history, nil
 }
 
 // Units is an optional argument for Client method ModifyOrder().
@@ -293,3 +549,134 @@ func (c *Client) CancelOrder(orderId Id) (*CancelOrderResponse, error) {
 	}
 	return &cor, nil
 }
+
+// maxOrdersCount is the maximum number of orders that Oanda returns for a single request.
+const maxOrdersCount = 500
+
+// allOrders returns all open orders that match args, which must not include Count or MaxId, by
+// requesting as many pages of orders as needed.
+func (c *Client) allOrders(args ...OrdersArg) ([]Order, error) {
+	var orders []Order
+	pageArgs := append([]OrdersArg{Count(maxOrdersCount)}, args...)
+	for {
+		page, err := c.Orders(pageArgs...)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, page...)
+		if len(page) < maxOrdersCount {
+			return orders, nil
+		}
+		pageArgs = append([]OrdersArg{Count(maxOrdersCount),
+			MaxId(page[len(page)-1].OrderId - 1)}, args...)
+	}
+}
+
+// CancelOrderResult holds the outcome of the cancellation of a single order.  Err is nil if the
+// order was cancelled successfully.
+type CancelOrderResult struct {
+	OrderId  Id
+	Response *CancelOrderResponse
+	Err      error
+}
+
+// CancelOrdersError is returned when one or more orders could not be cancelled.
+type CancelOrdersError struct {
+	Failed []CancelOrderResult
+}
+
+func (e *CancelOrdersError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		msgs[i] = fmt.Sprintf("order %d: %v", r.OrderId, r.Err)
+	}
+	return fmt.Sprintf("Failed to cancel %d order(s): %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+// CancelOrdersForInstrument cancels all open orders for instrument.  The result of every
+// cancellation is returned.  If any of the orders could not be cancelled a *CancelOrdersError that
+// holds the failed cancellations is returned as well.
+func (c *Client) CancelOrdersForInstrument(instrument string) ([]CancelOrderResult, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := c.allOrders(Instrument(instrument))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CancelOrderResult, len(orders))
+	cancelErr := CancelOrdersError{}
+	for i, o := range orders {
+		cor, err := c.CancelOrder(o.OrderId)
+		results[i] = CancelOrderResult{o.OrderId, cor, err}
+		if err != nil {
+			cancelErr.Failed = append(cancelErr.Failed, results[i])
+		}
+	}
+	if len(cancelErr.Failed) > 0 {
+		return results, &cancelErr
+	}
+	return results, nil
+}
+
+// ReplaceOrderError is returned by ReplaceOrder when the original order was cancelled but the
+// replacement order could not be created.
+type ReplaceOrderError struct {
+	OrderId   Id
+	Cancelled *CancelOrderResponse
+	Err       error
+}
+
+func (e *ReplaceOrderError) Error() string {
+	return fmt.Sprintf("Order %d was cancelled but its replacement failed: %v", e.OrderId, e.Err)
+}
+
+// ReplaceOrder cancels an open order and submits a new order with the same parameters, except for
+// those that are specified by args.  Supported args are Units(), Price(), Expiry(), LowerBound(),
+// UpperBound(), StopLoss(), TakeProfit() and TrailingStop().
+//
+// The original order is left unchanged if it can not be cancelled.  If the order is cancelled
+// but the replacement order can not be created a *ReplaceOrderError is returned.
+func (c *Client) ReplaceOrder(orderId Id, args ...ModifyOrderArg) (*OrderResponse, error) {
+	o, err := c.Order(orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{
+		"type":       {o.OrderType},
+		"side":       {o.Side},
+		"units":      {strconv.Itoa(o.Units)},
+		"instrument": {o.Instrument},
+		"price":      {formatDecimal(o.Price)},
+		"expiry":     {strconv.FormatInt(o.Expiry.Time().UTC().Unix(), 10)},
+	}
+	optional := map[string]float64{
+		"lowerBound":   o.LowerBound,
+		"upperBound":   o.UpperBound,
+		"stopLoss":     o.StopLoss,
+		"takeProfit":   o.TakeProfit,
+		"trailingStop": o.TrailingStop,
+	}
+	for k, f := range optional {
+		if f != 0 {
+			optionalArgs(data).SetFloat(k, f)
+		}
+	}
+	for _, arg := range args {
+		arg.applyModifyOrderArg(data)
+	}
+
+	cor, err := c.CancelOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := c.submitOrder(data)
+	if err != nil {
+		return nil, &ReplaceOrderError{OrderId: orderId, Cancelled: cor, Err: err}
+	}
+	return rsp, nil
+}
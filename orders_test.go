@@ -15,6 +15,7 @@
 package oanda_test
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/santegoeds/oanda"
@@ -33,13 +34,169 @@ func (ts *TestOrderSuite) SetUpSuite(c *check.C) {
 
 var _ = check.Suite(&TestOrderSuite{})
 
+type OrdersSuite struct{}
+
+var _ = check.Suite(&OrdersSuite{})
+
+func (s *OrdersSuite) TestCancelOrderEmptyResponse(c *check.C) {
+	tr := stubTransport{StatusCode: 200}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	rsp, err := client.CancelOrder(2)
+	c.Assert(err, check.IsNil)
+	c.Assert(rsp, check.NotNil)
+}
+
+func (s *OrdersSuite) TestNewOrderPriceFormat(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"instrument": "EUR_USD"}`}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	a, b := 1.1, 0.2
+	_, err := client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 0.00001, time.Now(),
+		oanda.StopLoss(a+b), oanda.UpperBound(0.5), oanda.LowerBound(1e-8))
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	req := tr.Requests[0]
+	c.Assert(req.FormValue("price"), check.Equals, "0.00001")
+	c.Assert(req.FormValue("stopLoss"), check.Equals, "1.3")
+	c.Assert(req.FormValue("upperBound"), check.Equals, "0.5")
+	c.Assert(req.FormValue("lowerBound"), check.Equals, "0.00000001")
+
+	_, err = client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 2e-9, time.Now(),
+		oanda.StopLoss(-2e-9), oanda.LowerBound(1e-9))
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 2)
+	req = tr.Requests[1]
+	c.Assert(req.FormValue("price"), check.Equals, "0.000000002")
+	c.Assert(req.FormValue("stopLoss"), check.Equals, "-0.000000002")
+	c.Assert(req.FormValue("lowerBound"), check.Equals, "0.000000001")
+}
+
+func (s *OrdersSuite) TestNewOrderBounds(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"instrument": "EUR_USD"}`}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err := client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 1.1, time.Now(),
+		oanda.UpperBound(1.0), oanda.LowerBound(0.9))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "bounds")
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	_, err = client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 1.1, time.Now(),
+		oanda.UpperBound(1.2), oanda.LowerBound(1.0))
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
+
+func (s *OrdersSuite) TestCancelOrdersForInstrument(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Body: `{"orders": [
+				{"id": 2, "instrument": "EUR_USD", "units": 1, "side": "buy"},
+				{"id": 1, "instrument": "EUR_USD", "units": 1, "side": "sell"}]}`},
+			{StatusCode: 200, Body: `{"id": 10, "instrument": "EUR_USD", "units": 1}`},
+			{StatusCode: 404, Body: `{"code": 1, "message": "Order not found"}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	results, err := client.CancelOrdersForInstrument("eur/usd")
+	c.Assert(tr.Requests, check.HasLen, 3)
+	c.Assert(tr.Requests[0].URL.Query().Get("instrument"), check.Equals, "EUR_USD")
+	c.Assert(tr.Requests[1].Method, check.Equals, "DELETE")
+	c.Assert(tr.Requests[1].URL.Path, check.Equals, "/v1/accounts/1/orders/2")
+	c.Assert(tr.Requests[2].URL.Path, check.Equals, "/v1/accounts/1/orders/1")
+
+	c.Assert(results, check.HasLen, 2)
+	c.Assert(results[0].OrderId, check.Equals, oanda.Id(2))
+	c.Assert(results[0].Err, check.IsNil)
+	c.Assert(results[0].Response.TransactionId, check.Equals, oanda.Id(10))
+	c.Assert(results[1].OrderId, check.Equals, oanda.Id(1))
+	c.Assert(results[1].Err, check.NotNil)
+
+	c.Assert(err, check.FitsTypeOf, &oanda.CancelOrdersError{})
+	c.Assert(err.(*oanda.CancelOrdersError).Failed, check.HasLen, 1)
+	c.Assert(err.(*oanda.CancelOrdersError).Failed[0].OrderId, check.Equals, oanda.Id(1))
+}
+
+func (s *OrdersSuite) TestReplaceOrder(c *check.C) {
+	order := `{"id": 1, "instrument": "EUR_USD", "units": 2, "side": "buy", "type": "limit",
+		"price": 1.1, "time": "1400000000000000", "expiry": "1400003600000000", "stopLoss": 1.0}`
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Body: order},
+			{StatusCode: 200, Body: `{"id": 10, "instrument": "EUR_USD"}`},
+			{StatusCode: 200, Body: `{"instrument": "EUR_USD", "time": "1400000001000000",
+				"price": 1.05, "orderOpened": {"id": 2, "units": 2, "side": "buy"}}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	rsp, err := client.ReplaceOrder(1, oanda.Price(1.05))
+	c.Assert(err, check.IsNil)
+	c.Assert(rsp.OrderOpened, check.NotNil)
+	c.Assert(rsp.OrderOpened.OrderId, check.Equals, oanda.Id(2))
+	c.Assert(tr.Requests, check.HasLen, 3)
+	c.Assert(tr.Requests[1].Method, check.Equals, "DELETE")
+	create := tr.Requests[2]
+	c.Assert(create.Method, check.Equals, "POST")
+	c.Assert(create.FormValue("price"), check.Equals, "1.05")
+	c.Assert(create.FormValue("units"), check.Equals, "2")
+	c.Assert(create.FormValue("type"), check.Equals, "limit")
+	c.Assert(create.FormValue("expiry"), check.Equals, "1400003600")
+	c.Assert(create.FormValue("stopLoss"), check.Equals, "1")
+
+	tr = stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Body: order},
+			{StatusCode: 200, Body: `{"id": 10, "instrument": "EUR_USD"}`},
+			{StatusCode: 400, Body: `{"code": 1, "message": "Invalid price"}`},
+		},
+	}
+	client = newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err = client.ReplaceOrder(1, oanda.Price(-1))
+	c.Assert(err, check.FitsTypeOf, &oanda.ReplaceOrderError{})
+	roErr := err.(*oanda.ReplaceOrderError)
+	c.Assert(roErr.OrderId, check.Equals, oanda.Id(1))
+	c.Assert(roErr.Cancelled.TransactionId, check.Equals, oanda.Id(10))
+	c.Assert(roErr.Err, check.FitsTypeOf, &oanda.ApiError{})
+}
+
+func (s *OrdersSuite) TestOrderExpiry(c *check.C) {
+	expiryTime := func(t time.Time) oanda.Time {
+		return oanda.Time(strconv.FormatInt(t.UnixNano()/1000, 10))
+	}
+
+	o := oanda.Order{Expiry: expiryTime(time.Now().Add(-time.Minute))}
+	c.Assert(o.IsExpired(), check.Equals, true)
+	c.Assert(o.TimeToExpiry(), check.Equals, time.Duration(0))
+
+	o = oanda.Order{Expiry: expiryTime(time.Now().Add(time.Hour))}
+	c.Assert(o.IsExpired(), check.Equals, false)
+	c.Assert(o.TimeToExpiry() > 59*time.Minute, check.Equals, true)
+	c.Assert(o.TimeToExpiry() <= time.Hour, check.Equals, true)
+
+	o = oanda.Order{}
+	c.Assert(o.IsExpired(), check.Equals, false)
+	c.Assert(o.TimeToExpiry(), check.Equals, time.Duration(0))
+}
+
 func (ts *TestOrderSuite) TestOrderApi(c *check.C) {
 	expiry := time.Now().Add(5 * time.Minute)
 
-	o, err := ts.Client.NewOrder(oanda.Limit, oanda.Buy, 2, "eur_usd", 0.75, expiry,
+	rsp, err := ts.Client.NewOrder(oanda.Limit, oanda.Buy, 2, "eur_usd", 0.75, expiry,
 		oanda.UpperBound(1.0), oanda.LowerBound(0.5))
 	c.Assert(err, check.IsNil)
-	c.Log(o)
+	c.Log(rsp)
+	o := rsp.OrderOpened
+	c.Assert(o, check.NotNil)
 	c.Assert(o.OrderId, check.Not(check.Equals), 0)
 	orderExpiry := o.Expiry.Time()
 	c.Assert(orderExpiry.Equal(expiry.Truncate(time.Second)), check.Equals, true)
@@ -90,10 +247,39 @@ func (ts *TestOrderSuite) TestOrderApi(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(o.Units, check.Equals, 1)
 
-	rsp, err := ts.Client.CancelOrder(o.OrderId)
+	cor, err := ts.Client.CancelOrder(o.OrderId)
 	c.Assert(err, check.IsNil)
-	c.Log("OrderCancelResponse:", rsp)
+	c.Log("OrderCancelResponse:", cor)
 	orders, err = ts.Client.Orders()
 	c.Assert(err, check.IsNil)
 	c.Assert(orders, check.HasLen, 0)
 }
+
+func (ts *TestOrderSuite) TestOrderHistory(c *check.C) {
+	start := time.Now().Add(-time.Minute)
+	expiry := time.Now().Add(5 * time.Minute)
+
+	rsp, err := ts.Client.NewOrder(oanda.Limit, oanda.Buy, 1, "eur_usd", 0.75, expiry)
+	c.Assert(err, check.IsNil)
+	o := rsp.OrderOpened
+	c.Assert(o, check.NotNil)
+	_, err = ts.Client.CancelOrder(o.OrderId)
+	c.Assert(err, check.IsNil)
+
+	history, err := ts.Client.OrderHistory(start, time.Now())
+	c.Assert(err, check.IsNil)
+	c.Log(history)
+
+	var found bool
+	for _, ol := range history {
+		if ol.OrderId == o.OrderId {
+			found = true
+			c.Assert(ol.Status, check.Equals, oanda.OrderCancelled)
+			c.Assert(ol.OrderType, check.Equals, string(oanda.Limit))
+			c.Assert(ol.Instrument, check.Equals, "EUR_USD")
+			c.Assert(ol.Price, check.Equals, 0.75)
+			c.Assert(ol.Reason, check.Equals, "CLIENT_REQUEST")
+		}
+	}
+	c.Assert(found, check.Equals, true)
+}
@@ -16,7 +16,7 @@ package oanda
 
 import (
 	"fmt"
-	"strings"
+	"math"
 )
 
 type (
@@ -45,6 +45,27 @@ type PositionCloseResponse struct {
 
 type Positions []Position
 
+// ForInstrument returns the position for instrument and true, or nil and false if there is no
+// position for instrument.
+func (ps Positions) ForInstrument(instrument string) (*Position, bool) {
+	instrument = instrumentKey(instrument)
+	for i := range ps {
+		if instrumentKey(ps[i].Instrument) == instrument {
+			return &ps[i], true
+		}
+	}
+	return nil, false
+}
+
+// Total returns the gross number of units of all positions, irrespective of their side.
+func (ps Positions) Total() int {
+	grossUnits := 0
+	for _, p := range ps {
+		grossUnits += p.Units
+	}
+	return grossUnits
+}
+
 // Positions returns all positions for the selected account.
 func (c *Client) Positions() (Positions, error) {
 	urlStr := fmt.Sprintf("/v1/accounts/%d/positions", c.accountId)
@@ -59,7 +80,10 @@ func (c *Client) Positions() (Positions, error) {
 
 // Position returns the position for the selected account and instrument.
 func (c *Client) Position(instrument string) (*Position, error) {
-	instrument = strings.ToUpper(instrument)
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	urlStr := fmt.Sprintf("/v1/accounts/%d/positions/%s", c.accountId, instrument)
 	p := Position{}
 	if err := getAndDecode(c, urlStr, &p); err != nil {
@@ -70,7 +94,10 @@ func (c *Client) Position(instrument string) (*Position, error) {
 
 // ClosePosition closes an existing position.
 func (c *Client) ClosePosition(instrument string) (*PositionCloseResponse, error) {
-	instrument = strings.ToUpper(instrument)
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	pcr := PositionCloseResponse{}
 	urlStr := fmt.Sprintf("/v1/accounts/%d/positions/%s", c.accountId, instrument)
 	if err := requestAndDecode(c, "DELETE", urlStr, nil, &pcr); err != nil {
@@ -78,3 +105,149 @@ func (c *Client) ClosePosition(instrument string) (*PositionCloseResponse, error
 	}
 	return &pcr, nil
 }
+
+// FinancingRate returns the net annual financing rate, in percent, of position p given the
+// financing rates of the base and quote currencies of its instrument as returned by
+// Client.FinancingRates.  A long position earns the bid rate of the base currency and pays the
+// ask rate of the quote currency; a short position earns the bid rate of the quote currency and
+// pays the ask rate of the base currency.  A positive rate is earned, a negative rate is paid.
+func (p Position) FinancingRate(rates map[string]InterestRate) (float64, error) {
+	base, quote := SplitInstrument(p.Instrument)
+	baseRate, ok := rates[base]
+	if !ok {
+		return 0, fmt.Errorf("No financing rate for currency %s", base)
+	}
+	quoteRate, ok := rates[quote]
+	if !ok {
+		return 0, fmt.Errorf("No financing rate for currency %s", quote)
+	}
+	if p.Side == string(Sell) {
+		return quoteRate.Bid - baseRate.Ask, nil
+	}
+	return baseRate.Bid - quoteRate.Ask, nil
+}
+
+// BlendedFinancingRate returns the net annual financing rate, in percent, of all positions of the
+// selected account weighted by the exposure of each position in the home currency of the account.
+// See Position.FinancingRate for the rate of a single position.  The daily carry of the account is
+// approximately the exposure of its positions times the blended rate / 100 / 365.  Zero is
+// returned if the account has no positions.
+func (c *Client) BlendedFinancingRate() (float64, error) {
+	positions, err := c.Positions()
+	if err != nil {
+		return 0, err
+	}
+	if len(positions) == 0 {
+		return 0, nil
+	}
+	cc, err := c.ConversionContext()
+	if err != nil {
+		return 0, err
+	}
+
+	var weighted, total float64
+	for _, p := range positions {
+		rates, err := c.FinancingRates(p.Instrument)
+		if err != nil {
+			return 0, err
+		}
+		rate, err := p.FinancingRate(rates)
+		if err != nil {
+			return 0, err
+		}
+		exposure, err := cc.Exposure(p.Instrument, p.Units)
+		if err != nil {
+			return 0, err
+		}
+		exposure = math.Abs(exposure)
+		weighted += exposure * rate
+		total += exposure
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	return weighted / total, nil
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// PositionPoller
+
+// A PositionPoller polls the positions of the selected account.  Oanda responds with Not
+// Modified if the positions did not change since the previous poll, in which case the previous
+// positions are returned.
+type PositionPoller struct {
+	pr            *PollRequest
+	lastPositions Positions
+}
+
+// NewPositionPoller returns a PositionPoller for the positions of the selected account.
+func (c *Client) NewPositionPoller() (*PositionPoller, error) {
+	pr, err := c.NewPollRequest(fmt.Sprintf("/v1/accounts/%d/positions", c.accountId))
+	if err != nil {
+		return nil, err
+	}
+	return &PositionPoller{pr: pr}, nil
+}
+
+// Poll returns the current positions of the selected account.
+func (pp *PositionPoller) Poll() (Positions, error) {
+	v := struct {
+		Positions Positions `json:"positions"`
+	}{}
+	modified, err := pp.pr.PollAndDecode(&v)
+	if err != nil {
+		return nil, err
+	}
+	if modified {
+		pp.lastPositions = v.Positions
+	}
+	return append(Positions{}, pp.lastPositions...), nil
+}
+
+// PositionChanges holds the differences between two sets of positions.
+type PositionChanges struct {
+	// Added are the positions in instruments in which there was no position before.
+	Added Positions
+	// Removed are the positions that were closed; they hold the last known values.
+	Removed Positions
+	// Modified are the positions of which the side, units or average price changed.
+	Modified Positions
+}
+
+// String implements the fmt.Stringer interface.
+func (pc PositionChanges) String() string {
+	return fmt.Sprintf("PositionChanges{Added: %v, Removed: %v, Modified: %v}", pc.Added,
+		pc.Removed, pc.Modified)
+}
+
+// IsEmpty returns true if the positions did not change.
+func (pc PositionChanges) IsEmpty() bool {
+	return len(pc.Added) == 0 && len(pc.Removed) == 0 && len(pc.Modified) == 0
+}
+
+// PollChanged polls the positions and returns how they differ from the positions of the
+// previous poll.  All positions are reported as added on the first poll.
+func (pp *PositionPoller) PollChanged() (*PositionChanges, error) {
+	prev := pp.lastPositions
+	current, err := pp.Poll()
+	if err != nil {
+		return nil, err
+	}
+
+	pc := PositionChanges{}
+	for _, p := range current {
+		old, ok := prev.ForInstrument(p.Instrument)
+		switch {
+		case !ok:
+			pc.Added = append(pc.Added, p)
+		case *old != p:
+			pc.Modified = append(pc.Modified, p)
+		}
+	}
+	for _, p := range prev {
+		if _, ok := current.ForInstrument(p.Instrument); !ok {
+			pc.Removed = append(pc.Removed, p)
+		}
+	}
+	return &pc, nil
+}
@@ -15,6 +15,9 @@
 package oanda_test
 
 import (
+	"math"
+	"net/http"
+
 	"github.com/santegoeds/oanda"
 
 	check "gopkg.in/check.v1"
@@ -31,10 +34,73 @@ func (ts *TestPositionSuite) SetUpSuite(c *check.C) {
 
 var _ = check.Suite(&TestPositionSuite{})
 
+type PositionsSuite struct{}
+
+var _ = check.Suite(&PositionsSuite{})
+
+func (s *PositionsSuite) TestPositionsHelpers(c *check.C) {
+	positions := oanda.Positions{
+		{Side: "buy", Instrument: "EUR_USD", Units: 3, AvgPrice: 1.1},
+		{Side: "sell", Instrument: "GBP_USD", Units: 2, AvgPrice: 1.5},
+	}
+
+	p, ok := positions.ForInstrument("eur_usd")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(p.Units, check.Equals, 3)
+	c.Assert(p.AvgPrice, check.Equals, 1.1)
+
+	p, ok = positions.ForInstrument("USD_JPY")
+	c.Assert(ok, check.Equals, false)
+	c.Assert(p, check.IsNil)
+
+	c.Assert(positions.Total(), check.Equals, 5)
+}
+
+func (s *PositionsSuite) TestPositionPoller(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Header: http.Header{"Etag": {"1"}}, Body: `{"positions": [
+				{"side": "buy", "instrument": "EUR_USD", "units": 3, "avgPrice": 1.1},
+				{"side": "sell", "instrument": "GBP_USD", "units": 2, "avgPrice": 1.5}]}`},
+			{StatusCode: 304},
+			{StatusCode: 200, Header: http.Header{"Etag": {"2"}}, Body: `{"positions": [
+				{"side": "buy", "instrument": "EUR_USD", "units": 5, "avgPrice": 1.2},
+				{"side": "buy", "instrument": "USD_JPY", "units": 1, "avgPrice": 120}]}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	pp, err := client.NewPositionPoller()
+	c.Assert(err, check.IsNil)
+
+	pc, err := pp.PollChanged()
+	c.Assert(err, check.IsNil)
+	c.Assert(pc.Added, check.HasLen, 2)
+	c.Assert(pc.Removed, check.HasLen, 0)
+	c.Assert(pc.Modified, check.HasLen, 0)
+
+	pc, err = pp.PollChanged()
+	c.Assert(err, check.IsNil)
+	c.Assert(pc.IsEmpty(), check.Equals, true)
+
+	pc, err = pp.PollChanged()
+	c.Assert(err, check.IsNil)
+	c.Assert(pc.Added, check.HasLen, 1)
+	c.Assert(pc.Added[0].Instrument, check.Equals, "USD_JPY")
+	c.Assert(pc.Removed, check.HasLen, 1)
+	c.Assert(pc.Removed[0].Instrument, check.Equals, "GBP_USD")
+	c.Assert(pc.Modified, check.HasLen, 1)
+	c.Assert(pc.Modified[0].Units, check.Equals, 5)
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/accounts/1/positions")
+}
+
 func (ts *TestPositionSuite) TestPositionsApi(c *check.C) {
-	t, err := ts.Client.NewTrade(oanda.Buy, 1, "eur_usd")
+	rsp, err := ts.Client.NewTrade(oanda.Buy, 1, "eur_usd")
 	c.Assert(err, check.IsNil)
-	c.Log(t)
+	c.Log(rsp)
+	t := rsp.TradeOpened
+	c.Assert(t, check.NotNil)
 
 	positions, err := ts.Client.Positions()
 	c.Assert(err, check.IsNil)
@@ -71,3 +137,38 @@ func (ts *TestPositionSuite) TestNonexistingPosition(c *check.C) {
 	c.Assert(ok, check.Equals, true)
 	c.Assert(apiErr.Code, check.Equals, 14)
 }
+
+func (s *PositionsSuite) TestBlendedFinancingRate(c *check.C) {
+	rates := `"interestRate": {"EUR": {"bid": 1, "ask": 1.5}, "USD": {"bid": 2, "ask": 2.5},
+		"JPY": {"bid": 0.1, "ask": 0.3}}`
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1": `{"accountId": 1, "accountCurrency": "USD"}`,
+			"/v1/accounts/1/positions": `{"positions": [
+				{"instrument": "EUR_USD", "side": "buy", "units": 10000},
+				{"instrument": "USD_JPY", "side": "sell", "units": 6000}]}`,
+			"/v1/instruments": `{"instruments": [
+				{"instrument": "EUR_USD", "pip": "0.0001", ` + rates + `},
+				{"instrument": "USD_JPY", "pip": "0.01", ` + rates + `}]}`,
+			"/v1/prices": `{"prices": [
+				{"instrument": "EUR_USD", "bid": 1.19, "ask": 1.21},
+				{"instrument": "USD_JPY", "bid": 119.9, "ask": 120.1}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	long := oanda.Position{Instrument: "EUR_USD", Side: "buy", Units: 1}
+	rate, err := long.FinancingRate(map[string]oanda.InterestRate{
+		"EUR": {Bid: 1, Ask: 1.5}, "USD": {Bid: 2, Ask: 2.5}})
+	c.Assert(err, check.IsNil)
+	c.Assert(rate, check.Equals, -1.5)
+	_, err = long.FinancingRate(map[string]oanda.InterestRate{"EUR": {Bid: 1, Ask: 1.5}})
+	c.Assert(err, check.NotNil)
+
+	// (12000 USD * -1.5% + 6000 USD * -2.4%) / 18000 USD
+	blended, err := client.BlendedFinancingRate()
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(blended+1.8) < 1e-9, check.Equals, true)
+}
@@ -15,14 +15,20 @@
 package oanda
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/santegoeds/oanda/analytics"
 )
 
 type Prices map[string]PriceTick
@@ -46,7 +52,22 @@ func (p *PriceTick) Spread() float64 {
 	return p.Ask - p.Bid
 }
 
+// Equal returns true if p and other have the same time and the same Bid and Ask prices.  The
+// status is ignored; use EqualWithStatus to compare it as well.
+func (p PriceTick) Equal(other PriceTick) bool {
+	return p.Time == other.Time && p.Bid == other.Bid && p.Ask == other.Ask
+}
+
+// EqualWithStatus is like Equal but also requires that p and other have the same status.
+func (p PriceTick) EqualWithStatus(other PriceTick) bool {
+	return p.Equal(other) && p.Status == other.Status
+}
+
 // PollPrices returns the latest PriceTick for the specified instruments.
+//
+// Polled prices do not require a selected account.  Unlike the prices of a PriceServer, which are
+// specific to the selected account if there is one, polled prices are always Oanda's general
+// prices and therefore may differ from the prices at which the account trades.
 func (c *Client) PollPrices(instruments ...string) (Prices, error) {
 	return c.PollPricesSince(time.Time{}, instruments...)
 }
@@ -56,7 +77,10 @@ func (c *Client) PollPrices(instruments ...string) (Prices, error) {
 // result.
 func (c *Client) PollPricesSince(since time.Time, instrs ...string) (Prices, error) {
 	if len(instrs) < 1 {
-		return nil, errors.New("ArgumentError: At least one instrument is required.")
+		return nil, &ValidationError{
+			Field:  "instruments",
+			Reason: "At least one instrument is required.",
+		}
 	}
 
 	pp, err := c.NewPricePoller(since, instrs...)
@@ -73,64 +97,70 @@ type PricePoller struct {
 
 // NewPricePoller returns a poller to repeatedly poll Oanda for updates of the same set of
 // instruments.
+//
+// Note that Oanda only returns the instruments whose prices changed since the previous poll (or
+// since the specified time for the first poll). The PricePoller merges these updates with the
+// ticks that it received earlier so that Poll always returns the latest known tick for every
+// instrument for which a tick has been received.
 func (c *Client) NewPricePoller(since time.Time, instrs ...string) (*PricePoller, error) {
 	if len(instrs) < 1 {
-		return nil, errors.New("ArgumentError: At least one instrument is required.")
+		return nil, &ValidationError{
+			Field:  "instruments",
+			Reason: "At least one instrument is required.",
+		}
 	}
-
-	req, err := c.NewRequest("GET", "/v1/prices", nil)
+	instrs, err := normalizeInstruments(instrs)
 	if err != nil {
 		return nil, err
 	}
-	q := req.URL.Query()
-	q.Set("instruments", strings.ToUpper(strings.Join(instrs, ",")))
+
+	q := url.Values{}
+	q.Set("instruments", strings.Join(instrs, ","))
 	if !since.IsZero() {
 		q.Set("since", strconv.FormatInt(since.UTC().Unix(), 10))
 	}
-	req.URL.RawQuery = q.Encode()
+	pr, err := c.NewPollRequest("/v1/prices?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
 	pp := PricePoller{
-		pr:         &PollRequest{c, req},
+		pr:         pr,
 		lastPrices: make(Prices),
 	}
-	return &pp, err
+	return &pp, nil
+}
+
+// PollRequest returns the request that the PricePoller sends to Oanda, e.g. for logging.
+func (pp *PricePoller) PollRequest() *PollRequest {
+	return pp.pr
 }
 
 // Poll returns the most recent set of prices for the instruments with which the PricePoller
-// was configured.
+// was configured.  Instruments that were not updated since the previous poll retain their last
+// known tick.
 func (pp *PricePoller) Poll() (Prices, error) {
-	rsp, err := pp.pr.Poll()
-	if err != nil {
-		return nil, err
-	}
-	defer closeResponse(rsp.Body)
-	if rsp.ContentLength == 0 {
-		return pp.lastPrices, nil
-	}
-
-	dec := json.NewDecoder(rsp.Body)
-	if rsp.StatusCode >= 400 {
-		apiErr := ApiError{}
-		if err = dec.Decode(&apiErr); err != nil {
-			return nil, err
-		}
-		return nil, &apiErr
-	}
-
 	v := struct {
 		Prices []struct {
 			Instrument string `json:"instrument"`
 			PriceTick
 		} `json:"prices"`
 	}{}
-	if err = dec.Decode(&v); err != nil {
+	if _, err := pp.pr.PollAndDecode(&v); err != nil {
 		return nil, err
 	}
-	prices := make(Prices)
 	for _, p := range v.Prices {
-		prices[p.Instrument] = p.PriceTick
+		pp.lastPrices[p.Instrument] = p.PriceTick
+		pp.pr.c.observeTickStatus(p.Instrument, p.Status)
 	}
-	pp.lastPrices = prices
-	return prices, nil
+	return pp.lastPrices.clone(), nil
+}
+
+func (p Prices) clone() Prices {
+	c := make(Prices, len(p))
+	for instr, tick := range p {
+		c[instr] = tick
+	}
+	return c
 }
 
 type instrumentTick struct {
@@ -142,6 +172,112 @@ var tickPool = sync.Pool{
 	New: func() interface{} { return &instrumentTick{} },
 }
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Triangle
+
+// A Triangle compares the mid price of a cross with the mid price that is implied by two legs that
+// share a currency; e.g. EUR_JPY with EUR_USD and USD_JPY.
+type Triangle struct {
+	Cross   string
+	Legs    [2]string
+	Prices  Prices
+	Actual  float64
+	Implied float64
+	// DiscrepancyPips is Actual - Implied expressed in pips of Cross.
+	DiscrepancyPips float64
+}
+
+// String implements the fmt.Stringer interface.
+func (t Triangle) String() string {
+	return fmt.Sprintf("Triangle{Cross: %s, Legs: %v, Actual: %v, Implied: %v, "+
+		"DiscrepancyPips: %.1f}", t.Cross, t.Legs, t.Actual, t.Implied, t.DiscrepancyPips)
+}
+
+// CheckTriangle polls the prices of cross and of legs leg1 and leg2 in a single request and
+// compares the mid price of cross with the mid price implied by the legs.  The legs must connect
+// the base and quote currencies of cross through a common currency, in any order and orientation;
+// e.g. CheckTriangle("EUR_USD", "USD_JPY", "EUR_JPY") or CheckTriangle("USD_JPY", "EUR_USD",
+// "EUR_JPY").
+func (c *Client) CheckTriangle(leg1, leg2, cross string) (*Triangle, error) {
+	instrs, err := normalizeInstruments([]string{leg1, leg2, cross})
+	if err != nil {
+		return nil, err
+	}
+	leg1, leg2, cross = instrs[0], instrs[1], instrs[2]
+
+	base, quote := SplitInstrument(cross)
+	via, ok := triangleVia(base, leg1, leg2)
+	if !ok {
+		via, ok = triangleVia(base, leg2, leg1)
+		leg1, leg2 = leg2, leg1
+	}
+	if !ok || !hasCurrencies(leg2, via, quote) {
+		return nil, &ValidationError{
+			Field: "instruments",
+			Reason: fmt.Sprintf("%s and %s do not form a triangle with %s.",
+				instrs[0], instrs[1], cross),
+		}
+	}
+
+	info, err := c.InstrumentInfo(cross)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := c.PollPrices(leg1, leg2, cross)
+	if err != nil {
+		return nil, err
+	}
+	for _, instr := range instrs {
+		if _, ok := prices[instr]; !ok {
+			return nil, fmt.Errorf("No price for instrument %s", instr)
+		}
+	}
+
+	rate := func(instr, from string) float64 {
+		tick := prices[instr]
+		mid := (tick.Bid + tick.Ask) / 2
+		if b, _ := SplitInstrument(instr); b == from {
+			return mid
+		}
+		return 1 / mid
+	}
+
+	t := Triangle{
+		Cross:   cross,
+		Legs:    [2]string{leg1, leg2},
+		Prices:  prices,
+		Actual:  rate(cross, base),
+		Implied: rate(leg1, base) * rate(leg2, via),
+	}
+	if info.Pip > 0 {
+		t.DiscrepancyPips = info.PriceToPips(t.Actual - t.Implied)
+	}
+	return &t, nil
+}
+
+// triangleVia returns the currency other than from of instrument leg if leg contains currency from
+// and other does not.
+func triangleVia(from, leg, other string) (string, bool) {
+	b, q := SplitInstrument(leg)
+	switch {
+	case b == from && !hasCurrency(other, from):
+		return q, true
+	case q == from && !hasCurrency(other, from):
+		return b, true
+	}
+	return "", false
+}
+
+func hasCurrency(instrument, currency string) bool {
+	b, q := SplitInstrument(instrument)
+	return b == currency || q == currency
+}
+
+func hasCurrencies(instrument, c1, c2 string) bool {
+	b, q := SplitInstrument(instrument)
+	return (b == c1 && q == c2) || (b == c2 && q == c1)
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // priceServer
 
@@ -152,18 +288,83 @@ type PriceServer struct {
 	// If HeartbeatFunc is not nil it is invoked once for every heartbeat message that the
 	// PriceServer receives.
 	HeartbeatFunc HeartbeatHandlerFunc
-	srv           *messageServer
-	chanMap       *tickChans
+
+	// If SpreadTracker is not nil it is updated with every Tick that the PriceServer receives.
+	SpreadTracker *SpreadTracker
+
+	// If CandleTracker is not nil it is updated with every Tick that the PriceServer receives
+	// before the Tick is handled.
+	CandleTracker *CandleTracker
+
+	// CandleTrackers are updated in the same way as CandleTracker.  Use these to build candles
+	// for several granularities, and set CandleTracker.CompleteFunc to receive the candles as they
+	// complete alongside the ticks.
+	CandleTrackers []*CandleTracker
+
+	// If VWAPTracker is not nil it is updated with every Tick that the PriceServer receives.
+	VWAPTracker *VWAPTracker
+
+	// If CoalesceInterval is greater than zero the handler is invoked at most once per
+	// CoalesceInterval for every instrument.  Ticks that arrive within the interval are
+	// coalesced and only the most recent of these is delivered at the end of the interval.
+	CoalesceInterval time.Duration
+
+	// If RequireOpenMarket is true ConnectAndHandle returns ErrMarketClosed instead of
+	// connecting when trading in all instruments of the PriceServer is halted, for instance
+	// during the weekend.
+	RequireOpenMarket bool
+
+	// If UnexpectedTickFunc is not nil it is invoked for every Tick that the PriceServer
+	// receives for an instrument to which it did not subscribe.  Such ticks are logged and
+	// otherwise dropped.
+	UnexpectedTickFunc TickHandlerFunc
+
+	// Backoff determines the delays between reconnection attempts after the stream connection
+	// failed.  DefaultBackoff is used if Backoff is nil.
+	Backoff BackoffFunc
+
+	// If ReconnectFunc is not nil it is invoked every time that the PriceServer retries its
+	// stream connection.  See ReconnectFunc.
+	ReconnectFunc ReconnectFunc
+
+	// StallTimeout is the time after which the stream connection is considered stalled if
+	// neither a tick nor a heartbeat arrives, in which case the PriceServer reconnects.  The
+	// default of defaultPriceStallTimeout is used if StallTimeout is not greater than zero.
+	StallTimeout time.Duration
+
+	srv      *messageServer
+	chanMap  *tickChans
+	handlers sync.WaitGroup
+	drainMtx sync.Mutex
+	drain    bool
 }
 
+// defaultPriceStallTimeout is the default PriceServer.StallTimeout.
+const defaultPriceStallTimeout = 10 * time.Second
+
 // NewPriceServer returns a PriceServer instance for receiving and handling Ticks.
+//
+// The prices that are streamed depend on account selection.  With a selected account the
+// PriceServer receives the prices that apply to that account, otherwise it receives Oanda's general
+// prices.  Account selection is fixed when the PriceServer is created.
 func (c *Client) NewPriceServer(instrs ...string) (*PriceServer, error) {
 	if len(instrs) < 1 {
-		return nil, errors.New("ArgumentError: At least one instrument is required.")
+		return nil, &ValidationError{
+			Field:  "instruments",
+			Reason: "At least one instrument is required.",
+		}
 	}
 
-	for i, instr := range instrs {
-		instrs[i] = strings.ToUpper(instr)
+	instrs, err := normalizeInstruments(instrs)
+	if err != nil {
+		return nil, err
+	}
+	if MaxInstrumentsPerStream > 0 && len(instrs) > MaxInstrumentsPerStream {
+		return nil, &ValidationError{
+			Field: "instruments",
+			Reason: fmt.Sprintf("%d instruments exceed the maximum of %d per stream "+
+				"connection; use a MultiPriceServer instead.", len(instrs), MaxInstrumentsPerStream),
+		}
 	}
 
 	req, err := c.NewRequest("GET", "/v1/prices", nil)
@@ -175,7 +376,9 @@ func (c *Client) NewPriceServer(instrs ...string) (*PriceServer, error) {
 	u := req.URL
 	q := u.Query()
 	q.Set("instruments", strings.Join(instrs, ","))
-	q.Set("accountId", strconv.FormatUint(uint64(c.accountId), 10))
+	if c.accountId != 0 {
+		q.Set("accountId", strconv.FormatUint(uint64(c.accountId), 10))
+	}
 	u.RawQuery = q.Encode()
 
 	ps := PriceServer{
@@ -187,7 +390,7 @@ func (c *Client) NewPriceServer(instrs ...string) (*PriceServer, error) {
 		handleHeartbeatsFn: ps.handleHeartbeats,
 	}
 
-	if srv, err := c.newMessageServer(req, &streamSrv, time.Second*10); err != nil {
+	if srv, err := c.newMessageServer(req, &streamSrv, defaultPriceStallTimeout); err != nil {
 		return nil, err
 	} else {
 		ps.srv = srv
@@ -196,10 +399,56 @@ func (c *Client) NewPriceServer(instrs ...string) (*PriceServer, error) {
 	return &ps, nil
 }
 
+// ErrMarketClosed is returned by PriceServer.ConnectAndHandle if RequireOpenMarket is set and
+// trading in all instruments is halted.
+var ErrMarketClosed = errors.New("Market closed: trading in all instruments is halted")
+
 // ConnectAndHandle connects to the Oanda server and invokes handleFn for every Tick received.
 func (ps *PriceServer) ConnectAndHandle(handleFn TickHandlerFunc) error {
+	if ps.RequireOpenMarket {
+		if err := ps.checkMarketOpen(); err != nil {
+			return err
+		}
+	}
+	ps.setDrain(false)
 	ps.initServer(handleFn)
-	return ps.srv.ConnectAndDispatch()
+	ps.srv.backoff = ps.Backoff
+	ps.srv.reconnectFn = ps.ReconnectFunc
+	ps.srv.stallTimeout = defaultPriceStallTimeout
+	if ps.StallTimeout > 0 {
+		ps.srv.stallTimeout = ps.StallTimeout
+	}
+	err := ps.srv.ConnectAndDispatch()
+	if ps.draining() {
+		ps.handlers.Wait()
+	}
+	return err
+}
+
+// checkMarketOpen returns ErrMarketClosed if trading in all instruments is halted.
+func (ps *PriceServer) checkMarketOpen() error {
+	for _, instr := range ps.chanMap.Instruments() {
+		open, err := ps.srv.c.IsMarketOpen(instr)
+		if err != nil {
+			return err
+		}
+		if open {
+			return nil
+		}
+	}
+	return ErrMarketClosed
+}
+
+// LastHeartbeat returns the time at which the PriceServer last received a heartbeat or the zero
+// time if no heartbeat has been received.
+func (ps *PriceServer) LastHeartbeat() time.Time {
+	return ps.srv.LastHeartbeat()
+}
+
+// LastMessage returns the time at which the PriceServer last received a tick or the zero time if
+// no tick has been received.
+func (ps *PriceServer) LastMessage() time.Time {
+	return ps.srv.LastMessage()
 }
 
 // Stop terminates the Price server.
@@ -207,18 +456,113 @@ func (ps *PriceServer) Stop() {
 	ps.srv.Stop()
 }
 
+// StopAndDrain terminates the PriceServer in the same way as Stop, except that the ticks that were
+// received but not yet handled are still delivered to the handler before ConnectAndHandle
+// returns.  With a CoalesceInterval the pending tick of every instrument is delivered as well.
+// StopAndDrain does not wait for the ticks to be delivered and can be called from a handler.
+func (ps *PriceServer) StopAndDrain() {
+	ps.setDrain(true)
+	ps.srv.Stop()
+}
+
+func (ps *PriceServer) setDrain(drain bool) {
+	ps.drainMtx.Lock()
+	defer ps.drainMtx.Unlock()
+	ps.drain = drain
+}
+
+func (ps *PriceServer) draining() bool {
+	ps.drainMtx.Lock()
+	defer ps.drainMtx.Unlock()
+	return ps.drain
+}
+
 func (ps *PriceServer) initServer(handleFn TickHandlerFunc) {
 	handleTicks := func(tickC <-chan *instrumentTick) {
 		for tick := range tickC {
+			ps.trackTick(tick)
 			handleFn(tick.Instrument, tick.PriceTick)
 			tickPool.Put(tick)
 		}
 	}
+	if ps.CoalesceInterval > 0 {
+		handleTicks = func(tickC <-chan *instrumentTick) {
+			ps.coalesceTicks(tickC, handleFn)
+		}
+	}
 
 	for _, instr := range ps.chanMap.Instruments() {
 		tickC := make(chan *instrumentTick, defaultBufferSize)
 		ps.chanMap.Set(instr, tickC)
-		go handleTicks(tickC)
+		ps.handlers.Add(1)
+		go func() {
+			defer ps.handlers.Done()
+			handleTicks(tickC)
+		}()
+	}
+}
+
+// trackTick updates the trackers of the PriceServer with tick.
+func (ps *PriceServer) trackTick(tick *instrumentTick) {
+	if ps.SpreadTracker != nil {
+		ps.SpreadTracker.Update(tick.Instrument, tick.PriceTick)
+	}
+	if ps.CandleTracker != nil {
+		ps.CandleTracker.Update(tick.Instrument, tick.PriceTick)
+	}
+	for _, ct := range ps.CandleTrackers {
+		ct.Update(tick.Instrument, tick.PriceTick)
+	}
+	if ps.VWAPTracker != nil {
+		ps.VWAPTracker.Update(tick.Instrument, tick.PriceTick)
+	}
+}
+
+// coalesceTicks invokes handleFn for the ticks received on tickC such that handleFn is invoked at
+// most once per ps.CoalesceInterval.  A tick that arrives after a quiet interval is delivered
+// immediately, otherwise the most recent tick is delivered at the end of the interval.
+func (ps *PriceServer) coalesceTicks(tickC <-chan *instrumentTick, handleFn TickHandlerFunc) {
+	var (
+		instr     string
+		pending   PriceTick
+		isPending bool
+		delivered time.Time
+		timerC    <-chan time.Time
+	)
+
+	clock := ps.srv.c.clock()
+	deliver := func(instr string, pt PriceTick) {
+		handleFn(instr, pt)
+		delivered = clock.Now()
+	}
+
+	for {
+		select {
+		case tick, ok := <-tickC:
+			if !ok {
+				if isPending && ps.draining() {
+					deliver(instr, pending)
+				}
+				return
+			}
+			ps.trackTick(tick)
+			instr, pending = tick.Instrument, tick.PriceTick
+			tickPool.Put(tick)
+
+			if isPending {
+				continue
+			}
+			if wait := ps.CoalesceInterval - clock.Now().Sub(delivered); wait > 0 {
+				isPending = true
+				timerC = clock.After(wait)
+			} else {
+				deliver(instr, pending)
+			}
+
+		case <-timerC:
+			isPending, timerC = false, nil
+			deliver(instr, pending)
+		}
 	}
 }
 
@@ -248,15 +592,610 @@ func (ps *PriceServer) handleMessages(msgC <-chan StreamMessage) {
 			log.Printf("failed to unnarshal message %v", msg)
 			continue
 		}
+		ps.srv.c.observeTickStatus(tick.Instrument, tick.Status)
 		tickC, ok := ps.chanMap.Get(tick.Instrument)
 		if !ok {
 			log.Printf("unexpected instrument %v", tick.Instrument)
+			if ps.UnexpectedTickFunc != nil {
+				ps.UnexpectedTickFunc(tick.Instrument, tick.PriceTick)
+			}
 		} else if tickC != nil {
 			tickC <- tick
 		}
 	}
 }
 
+// WaitForPrice streams the prices of instrument until predicate returns true for a tick and
+// returns that tick.  The error returned by ctx.Err() is returned if ctx is done before the
+// predicate is satisfied.
+func (c *Client) WaitForPrice(ctx context.Context, instrument string,
+	predicate func(PriceTick) bool) (*PriceTick, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ps, err := c.NewPriceServer(instrument)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	tickC := make(chan PriceTick, 1)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- ps.ConnectAndHandle(func(instr string, tick PriceTick) {
+			if predicate(tick) {
+				once.Do(func() {
+					tickC <- tick
+					ps.Stop()
+				})
+			}
+		})
+	}()
+
+	select {
+	case tick := <-tickC:
+		return &tick, nil
+	case err := <-errC:
+		select {
+		case tick := <-tickC:
+			return &tick, nil
+		default:
+		}
+		if err == nil {
+			err = errors.New("price stream closed before the price condition was met")
+		}
+		return nil, err
+	case <-ctx.Done():
+		// A Stop that precedes the start of the stream has no effect, so keep stopping the server
+		// until ConnectAndHandle returns.
+		ticker := time.NewTicker(stopRetryInterval)
+		defer ticker.Stop()
+		for {
+			ps.Stop()
+			select {
+			case <-errC:
+				return nil, ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// stopRetryInterval is the interval at which WaitForPrice repeats Stop until the PriceServer has
+// terminated.
+const stopRetryInterval = 10 * time.Millisecond
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// MultiPriceServer
+
+// MaxInstrumentsPerStream is the maximum number of instruments that a PriceServer subscribes to
+// over a single stream connection.  NewPriceServer rejects more instruments, whereas a
+// MultiPriceServer spreads them over several connections.  A value less than 1 disables the limit,
+// in which case a MultiPriceServer subscribes to all instruments over a single connection.
+var MaxInstrumentsPerStream = 50
+
+// A MultiPriceServer receives PriceTicks for any number of instruments.  Instruments are spread
+// over as many PriceServers as is required to stay within MaxInstrumentsPerStream instruments per
+// stream connection.
+type MultiPriceServer struct {
+	// If HeartbeatFunc is not nil it is invoked once for every heartbeat message that any of the
+	// underlying PriceServers receives.
+	HeartbeatFunc HeartbeatHandlerFunc
+
+	// If SpreadTracker is not nil it is updated with every Tick that any of the underlying
+	// PriceServers receives.
+	SpreadTracker *SpreadTracker
+
+	// If CandleTracker is not nil it is updated with every Tick that any of the underlying
+	// PriceServers receives.
+	CandleTracker *CandleTracker
+
+	// CandleTrackers are updated with every Tick that any of the underlying PriceServers
+	// receives.  See PriceServer.CandleTrackers.
+	CandleTrackers []*CandleTracker
+
+	// If VWAPTracker is not nil it is updated with every Tick that any of the underlying
+	// PriceServers receives.
+	VWAPTracker *VWAPTracker
+
+	// CoalesceInterval limits the rate at which ticks are delivered per instrument.  See
+	// PriceServer.CoalesceInterval.
+	CoalesceInterval time.Duration
+
+	// If UnexpectedTickFunc is not nil it is invoked for every Tick that any of the underlying
+	// PriceServers receives for an instrument to which it did not subscribe.
+	UnexpectedTickFunc TickHandlerFunc
+
+	// Backoff determines the delays between reconnection attempts of each of the underlying
+	// PriceServers.  See PriceServer.Backoff.
+	Backoff BackoffFunc
+
+	// If ReconnectFunc is not nil it is invoked every time that any of the underlying
+	// PriceServers retries its stream connection.
+	ReconnectFunc ReconnectFunc
+
+	// StallTimeout is the stall timeout of each of the underlying PriceServers.  See
+	// PriceServer.StallTimeout.
+	StallTimeout time.Duration
+
+	servers []*PriceServer
+
+	stopMtx  sync.Mutex
+	stopMode multiStopMode
+	// stopC signals ConnectAndHandle that Stop or StopAndDrain was called.
+	stopC chan struct{}
+}
+
+// multiStopMode records how a MultiPriceServer was stopped.
+type multiStopMode int
+
+const (
+	notStopped multiStopMode = iota
+	stopped
+	stoppedAndDrained
+)
+
+// NewMultiPriceServer returns a MultiPriceServer instance for receiving and handling Ticks.
+func (c *Client) NewMultiPriceServer(instrs ...string) (*MultiPriceServer, error) {
+	if len(instrs) < 1 {
+		return nil, &ValidationError{
+			Field:  "instruments",
+			Reason: "At least one instrument is required.",
+		}
+	}
+
+	instrs, err := normalizeInstruments(instrs)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(instrs))
+	unique := make([]string, 0, len(instrs))
+	for _, instr := range instrs {
+		if !seen[instr] {
+			seen[instr] = true
+			unique = append(unique, instr)
+		}
+	}
+
+	shardSize := MaxInstrumentsPerStream
+	if shardSize < 1 {
+		shardSize = len(unique)
+	}
+
+	mps := MultiPriceServer{stopC: make(chan struct{}, 1)}
+	for start := 0; start < len(unique); start += shardSize {
+		end := start + shardSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		ps, err := c.NewPriceServer(unique[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		mps.servers = append(mps.servers, ps)
+	}
+	return &mps, nil
+}
+
+// ConnectAndHandle connects to the Oanda server and invokes handleFn for every Tick received on
+// any of the stream connections.  As with a PriceServer, handleFn may be invoked concurrently for
+// different instruments.  ConnectAndHandle returns when all connections are closed.  If one of the
+// connections fails, all others are stopped and the first error is returned.
+func (mps *MultiPriceServer) ConnectAndHandle(handleFn TickHandlerFunc) error {
+	errC := make(chan error, len(mps.servers))
+	for _, ps := range mps.servers {
+		ps.HeartbeatFunc = mps.HeartbeatFunc
+		ps.SpreadTracker = mps.SpreadTracker
+		ps.CandleTracker = mps.CandleTracker
+		ps.CandleTrackers = mps.CandleTrackers
+		ps.VWAPTracker = mps.VWAPTracker
+		ps.CoalesceInterval = mps.CoalesceInterval
+		ps.UnexpectedTickFunc = mps.UnexpectedTickFunc
+		ps.Backoff = mps.Backoff
+		ps.StallTimeout = mps.StallTimeout
+		ps.ReconnectFunc = mps.ReconnectFunc
+		go func(ps *PriceServer) {
+			errC <- ps.ConnectAndHandle(handleFn)
+		}(ps)
+	}
+
+	var (
+		firstErr error
+		retryC   <-chan time.Time
+	)
+	for pending := len(mps.servers); pending > 0; {
+		select {
+		case err := <-errC:
+			pending--
+			if err != nil && firstErr == nil {
+				firstErr = err
+				mps.Stop()
+			}
+		case <-mps.stopC:
+			// A PriceServer that has not started its stream yet misses the Stop, so the Stop is
+			// repeated until all PriceServers have returned.
+			if retryC == nil {
+				ticker := time.NewTicker(stopRetryInterval)
+				defer ticker.Stop()
+				retryC = ticker.C
+			}
+		case <-retryC:
+			mps.stopServers()
+		}
+	}
+	mps.setStopMode(notStopped)
+	return firstErr
+}
+
+// Stop terminates all stream connections of the MultiPriceServer.  Stop can be called before
+// ConnectAndHandle has started all of its connections.
+func (mps *MultiPriceServer) Stop() {
+	mps.setStopMode(stopped)
+	mps.stopServers()
+}
+
+// StopAndDrain terminates all underlying PriceServers.  See PriceServer.StopAndDrain.
+func (mps *MultiPriceServer) StopAndDrain() {
+	mps.setStopMode(stoppedAndDrained)
+	mps.stopServers()
+}
+
+// setStopMode records how the MultiPriceServer is stopped and, unless mode is notStopped,
+// signals ConnectAndHandle.
+func (mps *MultiPriceServer) setStopMode(mode multiStopMode) {
+	mps.stopMtx.Lock()
+	defer mps.stopMtx.Unlock()
+	mps.stopMode = mode
+	if mode == notStopped {
+		select {
+		case <-mps.stopC:
+		default:
+		}
+		return
+	}
+	select {
+	case mps.stopC <- struct{}{}:
+	default:
+	}
+}
+
+// stopServers stops the underlying PriceServers as recorded by setStopMode.
+func (mps *MultiPriceServer) stopServers() {
+	mps.stopMtx.Lock()
+	mode := mps.stopMode
+	mps.stopMtx.Unlock()
+	for _, ps := range mps.servers {
+		if mode == stoppedAndDrained {
+			ps.StopAndDrain()
+		} else {
+			ps.Stop()
+		}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// SpreadTracker
+
+// A SpreadTracker maintains rolling spread statistics per instrument over the most recent ticks.
+// A SpreadTracker is safe for concurrent use.
+type SpreadTracker struct {
+	mtx     sync.Mutex
+	size    int
+	spreads map[string]*analytics.Window
+}
+
+// NewSpreadTracker returns a SpreadTracker that calculates statistics over the spreads of the
+// most recent size ticks of every instrument.
+func NewSpreadTracker(size int) *SpreadTracker {
+	return &SpreadTracker{
+		size:    size,
+		spreads: make(map[string]*analytics.Window),
+	}
+}
+
+// Update adds the spread of tick to the statistics of instrument.
+func (st *SpreadTracker) Update(instrument string, tick PriceTick) {
+	instrument = instrumentKey(instrument)
+
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	w, ok := st.spreads[instrument]
+	if !ok {
+		w = analytics.NewWindow(st.size)
+		st.spreads[instrument] = w
+	}
+	w.Push(tick.Spread())
+}
+
+// SpreadStats returns the minimum, average and maximum spread of instrument.  NaN values are
+// returned if no ticks were received for instrument.
+func (st *SpreadTracker) SpreadStats(instrument string) (min, avg, max float64) {
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	w, ok := st.spreads[instrumentKey(instrument)]
+	if !ok {
+		nan := math.NaN()
+		return nan, nan, nan
+	}
+	return w.Min(), w.Mean(), w.Max()
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CandleTracker
+
+// A CandleTracker builds the forming midpoint candle of every instrument from the ticks that it
+// is updated with, typically by a PriceServer, so that a tick handler can relate a tick to the
+// candle so far.  Candles start at multiples of the granularity since midnight UTC, which
+// matches the candles of Oanda for granularities of up to one hour; completed candles are
+// available from a CandleStreamer or through CompleteFunc.  A CandleTracker is safe for concurrent
+// use.
+type CandleTracker struct {
+	// If CompleteFunc is not nil it is invoked with the forming candle of an instrument, marked as
+	// complete, when the first tick beyond the end of that candle arrives.  A candle is therefore
+	// delivered with the first tick of the next candle, and no candles are delivered for periods
+	// without ticks.  When the CandleTracker is used by a PriceServer, CompleteFunc is invoked
+	// before the tick that completes the candle is handled and may be invoked concurrently for
+	// different instruments.
+	CompleteFunc CandleHandlerFunc
+
+	mtx         sync.Mutex
+	granularity Granularity
+	candles     map[string]*MidpointCandle
+}
+
+// NewCandleTracker returns a CandleTracker for candles with granularity.  Monthly candles are not
+// supported.
+func NewCandleTracker(granularity Granularity) (*CandleTracker, error) {
+	if granularity.Duration() == 0 {
+		return nil, &ValidationError{
+			Field:  "granularity",
+			Reason: fmt.Sprintf("Granularity %s is not supported.", granularity),
+		}
+	}
+	ct := CandleTracker{
+		granularity: granularity,
+		candles:     make(map[string]*MidpointCandle),
+	}
+	return &ct, nil
+}
+
+// Update adds tick to the forming candle of instrument.  A new candle is started if the tick
+// lies beyond the end of the forming candle, in which case the forming candle is passed to
+// CompleteFunc.  Ticks that are older than the forming candle are ignored.
+func (ct *CandleTracker) Update(instrument string, tick PriceTick) {
+	instrument = instrumentKey(instrument)
+	if completed, ok := ct.update(instrument, tick); ok && ct.CompleteFunc != nil {
+		ct.CompleteFunc(instrument, completed)
+	}
+}
+
+// update adds tick to the forming candle of instrument and returns the candle that the tick
+// completed, if any.
+func (ct *CandleTracker) update(instrument string, tick PriceTick) (MidpointCandle, bool) {
+	start := tick.Time.Time().Truncate(ct.granularity.Duration())
+	mid := (tick.Bid + tick.Ask) / 2
+
+	ct.mtx.Lock()
+	defer ct.mtx.Unlock()
+	candle, ok := ct.candles[instrument]
+	if ok {
+		candleStart := candle.Time.Time()
+		if start.Before(candleStart) {
+			return MidpointCandle{}, false
+		}
+		if start.Equal(candleStart) {
+			candle.HighMid = math.Max(candle.HighMid, mid)
+			candle.LowMid = math.Min(candle.LowMid, mid)
+			candle.CloseMid = mid
+			candle.Volume++
+			return MidpointCandle{}, false
+		}
+	}
+	ct.candles[instrument] = &MidpointCandle{
+		Time:     Time(strconv.FormatInt(start.UnixNano()/1000, 10)),
+		OpenMid:  mid,
+		HighMid:  mid,
+		LowMid:   mid,
+		CloseMid: mid,
+		Volume:   1,
+	}
+	if !ok {
+		return MidpointCandle{}, false
+	}
+	completed := *candle
+	completed.Complete = true
+	return completed, true
+}
+
+// Candle returns the forming candle of instrument.  The result is false if no ticks were
+// received for instrument.
+func (ct *CandleTracker) Candle(instrument string) (MidpointCandle, bool) {
+	ct.mtx.Lock()
+	defer ct.mtx.Unlock()
+	candle, ok := ct.candles[instrumentKey(instrument)]
+	if !ok {
+		return MidpointCandle{}, false
+	}
+	return *candle, true
+}
+
+// flush removes the forming candles of all instruments and returns them.
+func (ct *CandleTracker) flush() map[string]MidpointCandle {
+	ct.mtx.Lock()
+	defer ct.mtx.Unlock()
+	candles := make(map[string]MidpointCandle, len(ct.candles))
+	for instr, candle := range ct.candles {
+		candles[instr] = *candle
+	}
+	ct.candles = make(map[string]*MidpointCandle)
+	return candles
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CandleBuilder
+
+// A CandleBuilder builds midpoint candles from the ticks of a PriceServer rather than polling
+// Oanda for them.  Candles are built in the same way as by a CandleTracker: a candle is complete
+// once the first tick beyond its end arrives, and no candles are produced for periods in which
+// an instrument had no ticks.
+type CandleBuilder struct {
+	ps       *PriceServer
+	ct       *CandleTracker
+	stopMtx  sync.Mutex
+	stopFlag bool
+}
+
+// NewCandleBuilder returns a CandleBuilder for candles with granularity of the specified
+// instruments.  Monthly candles are not supported.
+func (c *Client) NewCandleBuilder(granularity Granularity, instrs ...string) (*CandleBuilder, error) {
+	ct, err := NewCandleTracker(granularity)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := c.NewPriceServer(instrs...)
+	if err != nil {
+		return nil, err
+	}
+	ps.CandleTracker = ct
+	cb := CandleBuilder{
+		ps: ps,
+		ct: ct,
+	}
+	return &cb, nil
+}
+
+// ConnectAndHandle connects to the Oanda server and invokes handleFn for every candle that is
+// completed.  When the CandleBuilder is stopped with Stop, the forming candles of all instruments
+// are passed to handleFn, with Complete set to false, before ConnectAndHandle returns.  HandleFn
+// may be invoked concurrently for different instruments.
+func (cb *CandleBuilder) ConnectAndHandle(handleFn CandleHandlerFunc) error {
+	cb.setStopped(false)
+	cb.ct.CompleteFunc = handleFn
+	err := cb.ps.ConnectAndHandle(func(string, PriceTick) {})
+	if !cb.stopped() {
+		return err
+	}
+	candles := cb.ct.flush()
+	instrs := make([]string, 0, len(candles))
+	for instr := range candles {
+		instrs = append(instrs, instr)
+	}
+	sort.Strings(instrs)
+	for _, instr := range instrs {
+		handleFn(instr, candles[instr])
+	}
+	return err
+}
+
+// Stop terminates the CandleBuilder and causes ConnectAndHandle to deliver the forming candles.
+// Stop does not wait for ConnectAndHandle to return and can be called from a handler.
+func (cb *CandleBuilder) Stop() {
+	cb.setStopped(true)
+	cb.ps.StopAndDrain()
+}
+
+func (cb *CandleBuilder) setStopped(stopped bool) {
+	cb.stopMtx.Lock()
+	defer cb.stopMtx.Unlock()
+	cb.stopFlag = stopped
+}
+
+func (cb *CandleBuilder) stopped() bool {
+	cb.stopMtx.Lock()
+	defer cb.stopMtx.Unlock()
+	return cb.stopFlag
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// VWAPTracker
+
+// A VWAPTracker maintains the volume weighted average mid price of every instrument over a rolling
+// time window.  The tick stream does not carry traded volumes, so every tick has a weight of one
+// and the VWAP is the average mid price of the ticks in the window.  The window ends at the time
+// of the most recent tick of the instrument.  A VWAPTracker is safe for concurrent use.
+type VWAPTracker struct {
+	mtx    sync.Mutex
+	window time.Duration
+	ticks  map[string]*vwapWindow
+}
+
+type vwapTick struct {
+	time time.Time
+	mid  float64
+}
+
+type vwapWindow struct {
+	ticks []vwapTick
+}
+
+// NewVWAPTracker returns a VWAPTracker that averages over the ticks of the most recent window.
+func NewVWAPTracker(window time.Duration) (*VWAPTracker, error) {
+	if window <= 0 {
+		return nil, &ValidationError{
+			Field:  "window",
+			Reason: "Window must be greater than zero.",
+		}
+	}
+	vt := VWAPTracker{
+		window: window,
+		ticks:  make(map[string]*vwapWindow),
+	}
+	return &vt, nil
+}
+
+// Update adds the mid price of tick to the window of instrument and drops the ticks that fall
+// outside of the window.  Ticks that are older than the window are ignored.
+func (vt *VWAPTracker) Update(instrument string, tick PriceTick) {
+	instrument = instrumentKey(instrument)
+	t := tick.Time.Time()
+	mid := (tick.Bid + tick.Ask) / 2
+
+	vt.mtx.Lock()
+	defer vt.mtx.Unlock()
+	w, ok := vt.ticks[instrument]
+	if !ok {
+		w = &vwapWindow{}
+		vt.ticks[instrument] = w
+	}
+	if n := len(w.ticks); n > 0 {
+		last := w.ticks[n-1].time
+		if !t.After(last.Add(-vt.window)) {
+			return
+		}
+		if t.Before(last) {
+			t = last
+		}
+	}
+	w.ticks = append(w.ticks, vwapTick{t, mid})
+
+	cutoff := t.Add(-vt.window)
+	i := 0
+	for i < len(w.ticks) && !w.ticks[i].time.After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		w.ticks = append(w.ticks[:0], w.ticks[i:]...)
+	}
+}
+
+// VWAP returns the volume weighted average mid price of instrument over the window.  NaN is
+// returned if no ticks were received for instrument.
+func (vt *VWAPTracker) VWAP(instrument string) float64 {
+	vt.mtx.Lock()
+	defer vt.mtx.Unlock()
+	w, ok := vt.ticks[instrumentKey(instrument)]
+	if !ok || len(w.ticks) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, tick := range w.ticks {
+		sum += tick.mid
+	}
+	return sum / float64(len(w.ticks))
+}
+
 type tickChans struct {
 	mtx sync.RWMutex
 	m   map[string]chan *instrumentTick
@@ -265,7 +1204,7 @@ type tickChans struct {
 func (tc *tickChans) Instruments() []string {
 	tc.mtx.RLock()
 	defer tc.mtx.RUnlock()
-	instruments := make([]string, len(tc.m))
+	instruments := make([]string, 0, len(tc.m))
 	for instr := range tc.m {
 		instruments = append(instruments, instr)
 	}
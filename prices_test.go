@@ -15,6 +15,17 @@
 package oanda_test
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/santegoeds/oanda"
@@ -65,6 +76,22 @@ func (ts *TestPricesSuite) TestPricePoller(c *check.C) {
 	c.Assert(prices, check.HasLen, 2)
 }
 
+func (ts *TestPricesSuite) TestPricePollerSince(c *check.C) {
+	pp, err := ts.Client.NewPricePoller(time.Now().Add(-time.Hour), "eur_usd", "gbp_usd")
+	c.Assert(err, check.IsNil)
+
+	prices, err := pp.Poll()
+	c.Assert(err, check.IsNil)
+	c.Log(prices)
+	c.Assert(prices, check.HasLen, 2)
+
+	// Subsequent polls only return updated instruments; the poller must retain the others.
+	prices, err = pp.Poll()
+	c.Assert(err, check.IsNil)
+	c.Log(prices)
+	c.Assert(prices, check.HasLen, 2)
+}
+
 func (ts *TestPricesSuite) TestPriceServer(c *check.C) {
 	ps, err := ts.Client.NewPriceServer("eur_usd", "eur_gbp")
 	c.Assert(err, check.IsNil)
@@ -136,3 +163,907 @@ func (ts *TestPricesSuite) TestPriceServerHeartbeat(c *check.C) {
 	})
 	c.Assert(err, check.IsNil)
 }
+
+type PriceServerSuite struct{}
+
+var _ = check.Suite(&PriceServerSuite{})
+
+func (s *PriceServerSuite) TestRetryAfter(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+		Script: []stubResponse{
+			{StatusCode: 429, Header: http.Header{"Retry-After": []string{"2"}}},
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+
+	start := time.Now()
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(start) >= 2*time.Second, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestHTMLStreamResponse(c *check.C) {
+	page := "<html><body>Down for maintenance</body></html>"
+	for _, header := range []http.Header{
+		{"Content-Type": {"text/html; charset=utf-8"}},
+		{},
+	} {
+		tr := stubTransport{
+			StatusCode: 200,
+			Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+			Script:     []stubResponse{{StatusCode: 200, Header: header, Body: page}},
+		}
+		client := newStubClient(c, &tr)
+
+		ps, err := client.NewPriceServer("eur_usd")
+		c.Assert(err, check.IsNil)
+
+		err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+			c.Error("unexpected tick")
+			ps.Stop()
+		})
+		c.Assert(err, check.FitsTypeOf, &oanda.StreamContentError{})
+		c.Assert(err.(*oanda.StreamContentError).Content, check.Equals, page)
+		c.Assert(tr.Requests, check.HasLen, 1)
+	}
+}
+
+func (s *PriceServerSuite) TestStreamDisconnect(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}
+			{"disconnect":{"code":64,"message":"bye","moreInfo":"http://developer.oanda.com"}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000001000000","bid":1.2,"ask":1.3}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.ReconnectFunc = func(attempt int, err error) {
+		c.Errorf("unexpected reconnect after %v", err)
+	}
+
+	var mtx sync.Mutex
+	ticks := 0
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		ticks++
+	})
+	c.Assert(err, check.DeepEquals, &oanda.StreamDisconnectError{
+		Code:     64,
+		Message:  "bye",
+		MoreInfo: "http://developer.oanda.com",
+	})
+	c.Assert(tr.Requests, check.HasLen, 1)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	c.Assert(ticks <= 1, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestStopAndDrain(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000001000000","bid":1.2,"ask":1.3}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000002000000","bid":1.3,"ask":1.4}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+
+	var (
+		mtx   sync.Mutex
+		ticks int
+	)
+	count := func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return ticks
+	}
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		mtx.Lock()
+		ticks++
+		first := ticks == 1
+		mtx.Unlock()
+		if first {
+			// Let the remaining ticks queue up before stopping.
+			time.Sleep(50 * time.Millisecond)
+			ps.StopAndDrain()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	delivered := count()
+	c.Assert(delivered >= 3, check.Equals, true, check.Commentf("%d ticks delivered", delivered))
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(count(), check.Equals, delivered)
+}
+
+func (s *PriceServerSuite) TestReconnectFunc(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+		Script:     []stubResponse{{StatusCode: 503}},
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.Backoff = oanda.FixedBackoff(10 * time.Millisecond)
+
+	var (
+		mtx      sync.Mutex
+		attempts []int
+		errs     []error
+	)
+	ps.ReconnectFunc = func(attempt int, err error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		attempts = append(attempts, attempt)
+		errs = append(errs, err)
+	}
+
+	ticks := 0
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		if ticks++; ticks == 2 {
+			ps.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	// The failed initial connection is retried, and so is the connection that ended after the
+	// first tick.
+	c.Assert(len(attempts) >= 2, check.Equals, true)
+	c.Assert(attempts[:2], check.DeepEquals, []int{1, 1})
+	c.Assert(errs[0], check.ErrorMatches, ".*Service Unavailable.*")
+	c.Assert(errs[1], check.Equals, io.EOF)
+}
+
+// redirectTransport sends every request to the server at Target.
+type redirectTransport struct {
+	Target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = t.Target.Scheme, t.Target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (t redirectTransport) CancelRequest(req *http.Request) {
+	http.DefaultTransport.(*http.Transport).CancelRequest(req)
+}
+
+func (s *PriceServerSuite) TestStallTimeout(c *check.C) {
+	var connections int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&connections, 1)
+		fmt.Fprintf(w, `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":%d,"ask":%d}}`,
+			n, n)
+		w.(http.Flusher).Flush()
+		// Stall until the client gives up on the connection.
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	target, err := url.Parse(srv.URL)
+	c.Assert(err, check.IsNil)
+
+	client, err := oanda.NewClient("fxpractice", "secret-token",
+		&http.Client{Transport: redirectTransport{target}})
+	c.Assert(err, check.IsNil)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.StallTimeout = 100 * time.Millisecond
+	ps.Backoff = oanda.FixedBackoff(10 * time.Millisecond)
+
+	start := time.Now()
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		// The second tick arrives on a new connection after the first one stalled.
+		if pp.Bid == 2 {
+			ps.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(atomic.LoadInt32(&connections) >= 2, check.Equals, true)
+	c.Assert(time.Since(start) < 2*time.Second, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestCandleBuilder(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		for _, tick := range []string{
+			`{"tick":{"instrument":"USD_JPY","time":"1400000010000000","bid":101,"ask":101}}`,
+			`{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.1}}`,
+			`{"tick":{"instrument":"EUR_USD","time":"1400000030000000","bid":1.3,"ask":1.3}}`,
+			`{"tick":{"instrument":"EUR_USD","time":"1400000040000000","bid":1.2,"ask":1.2}}`,
+			`{"tick":{"instrument":"EUR_USD","time":"1400000045000000","bid":1.4,"ask":1.4}}`,
+			`{"tick":{"instrument":"EUR_USD","time":"1400000200000000","bid":1.5,"ask":1.5}}`,
+		} {
+			fmt.Fprintln(w, tick)
+		}
+		w.(http.Flusher).Flush()
+		<-req.Context().Done()
+	}))
+	defer srv.Close()
+	target, err := url.Parse(srv.URL)
+	c.Assert(err, check.IsNil)
+
+	client, err := oanda.NewClient("fxpractice", "secret-token",
+		&http.Client{Transport: redirectTransport{target}})
+	c.Assert(err, check.IsNil)
+
+	_, err = client.NewCandleBuilder(oanda.M, "eur_usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	cb, err := client.NewCandleBuilder(oanda.M1, "eur_usd", "usd_jpy")
+	c.Assert(err, check.IsNil)
+
+	var (
+		mtx     sync.Mutex
+		candles = make(map[string][]oanda.MidpointCandle)
+	)
+	err = cb.ConnectAndHandle(func(instr string, candle oanda.MidpointCandle) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		candles[instr] = append(candles[instr], candle)
+		if instr == "EUR_USD" && len(candles[instr]) == 2 {
+			cb.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	// The third EUR_USD candle is the forming candle, which skips the minutes without ticks.
+	c.Assert(candles["EUR_USD"], check.DeepEquals, []oanda.MidpointCandle{
+		{Time: "1399999980000000", OpenMid: 1.1, HighMid: 1.3, LowMid: 1.1, CloseMid: 1.3,
+			Volume: 2, Complete: true},
+		{Time: "1400000040000000", OpenMid: 1.2, HighMid: 1.4, LowMid: 1.2, CloseMid: 1.4,
+			Volume: 2, Complete: true},
+		{Time: "1400000160000000", OpenMid: 1.5, HighMid: 1.5, LowMid: 1.5, CloseMid: 1.5,
+			Volume: 1},
+	})
+	c.Assert(candles["USD_JPY"], check.DeepEquals, []oanda.MidpointCandle{
+		{Time: "1399999980000000", OpenMid: 101, HighMid: 101, LowMid: 101, CloseMid: 101,
+			Volume: 1},
+	})
+}
+
+func (s *PriceServerSuite) TestBackoff(c *check.C) {
+	c.Assert(oanda.DefaultBackoff(1), check.Equals, time.Second)
+	c.Assert(oanda.DefaultBackoff(9), check.Equals, 256*time.Second)
+	c.Assert(oanda.DefaultBackoff(10) < 0, check.Equals, true)
+	c.Assert(oanda.FixedBackoff(500*time.Millisecond)(100), check.Equals, 500*time.Millisecond)
+
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+		Script: []stubResponse{
+			{StatusCode: 503},
+			{StatusCode: 503},
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	var attempts []int
+	ps.Backoff = func(attempt int) time.Duration {
+		attempts = append(attempts, attempt)
+		return 10 * time.Millisecond
+	}
+
+	start := time.Now()
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+	c.Assert(attempts, check.DeepEquals, []int{1, 2})
+
+	// A negative delay stops reconnecting.
+	failTr := stubTransport{StatusCode: 503}
+	failPs, err := newStubClient(c, &failTr).NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	failPs.Backoff = func(int) time.Duration { return -1 }
+	err = failPs.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {})
+	c.Assert(err, check.NotNil)
+	c.Assert(failTr.Requests, check.HasLen, 1)
+}
+
+func (s *PriceServerSuite) TestMultiPriceServer(c *check.C) {
+	instrs := make([]string, 120)
+	for i := range instrs {
+		instrs[i] = fmt.Sprintf("i%03d_usd", i)
+	}
+
+	// Every stream connection sends a tick for the first of its instruments.
+	tr := stubTransport{
+		StatusCode: 200,
+		BodyFunc: func(req *http.Request) string {
+			instr := strings.Split(req.URL.Query().Get("instruments"), ",")[0]
+			return fmt.Sprintf(
+				`{"tick":{"instrument":"%s","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+				instr)
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	mps, err := client.NewMultiPriceServer(instrs...)
+	c.Assert(err, check.IsNil)
+
+	mtx := sync.Mutex{}
+	received := make(map[string]bool)
+	err = mps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		received[instr] = true
+		if len(received) == 3 {
+			mps.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	c.Assert(received, check.DeepEquals, map[string]bool{
+		"I000_USD": true,
+		"I050_USD": true,
+		"I100_USD": true,
+	})
+
+	shards := make(map[string]bool)
+	for _, req := range tr.Requests {
+		shardInstrs := strings.Split(req.URL.Query().Get("instruments"), ",")
+		c.Assert(len(shardInstrs) <= oanda.MaxInstrumentsPerStream, check.Equals, true)
+		shards[shardInstrs[0]] = true
+	}
+	c.Assert(shards, check.HasLen, 3)
+}
+
+func (s *PriceServerSuite) TestMultiPriceServerStop(c *check.C) {
+	defer func(n int) { oanda.MaxInstrumentsPerStream = n }(oanda.MaxInstrumentsPerStream)
+	oanda.MaxInstrumentsPerStream = 1
+
+	connectAndHandle := func(mps *oanda.MultiPriceServer) <-chan error {
+		errC := make(chan error, 1)
+		go func() {
+			errC <- mps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {})
+		}()
+		return errC
+	}
+	wait := func(errC <-chan error) error {
+		select {
+		case err := <-errC:
+			return err
+		case <-time.After(2 * time.Second):
+			c.Fatal("MultiPriceServer did not stop")
+		}
+		return nil
+	}
+	tick := `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`
+
+	// The first connection fails immediately, which stops the other connections.
+	tr := stubTransport{StatusCode: 200, Body: tick}
+	client := newStubClient(c, &tr)
+	for i := 0; i < 20; i++ {
+		tr.Script = []stubResponse{{StatusCode: 400, Body: `{"code": 1, "message": "bad request"}`}}
+		mps, err := client.NewMultiPriceServer("eur_usd", "gbp_usd", "usd_jpy")
+		c.Assert(err, check.IsNil)
+		c.Assert(wait(connectAndHandle(mps)), check.FitsTypeOf, &oanda.ApiError{})
+	}
+
+	// Stop right after the start.
+	tr = stubTransport{StatusCode: 200, Body: tick}
+	client = newStubClient(c, &tr)
+	for i := 0; i < 20; i++ {
+		mps, err := client.NewMultiPriceServer("eur_usd", "gbp_usd", "usd_jpy")
+		c.Assert(err, check.IsNil)
+		errC := connectAndHandle(mps)
+		if i%2 == 0 {
+			mps.Stop()
+		} else {
+			mps.StopAndDrain()
+		}
+		c.Assert(wait(errC), check.IsNil)
+	}
+}
+
+func (s *PriceServerSuite) TestMaxInstrumentsPerStream(c *check.C) {
+	tr := stubTransport{StatusCode: 200}
+	client := newStubClient(c, &tr)
+
+	instrs := make([]string, oanda.MaxInstrumentsPerStream+1)
+	for i := range instrs {
+		instrs[i] = fmt.Sprintf("I%03d_USD", i)
+	}
+	_, err := client.NewPriceServer(instrs...)
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err, check.ErrorMatches, ".*exceed the maximum of 50.*MultiPriceServer.*")
+
+	_, err = client.NewPriceServer(instrs[1:]...)
+	c.Assert(err, check.IsNil)
+	_, err = client.NewMultiPriceServer(instrs...)
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 0)
+}
+
+func (s *PriceServerSuite) TestMultiPriceServerUnlimited(c *check.C) {
+	defer func(n int) { oanda.MaxInstrumentsPerStream = n }(oanda.MaxInstrumentsPerStream)
+	oanda.MaxInstrumentsPerStream = 0
+
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"I000_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	instrs := make([]string, 120)
+	for i := range instrs {
+		instrs[i] = fmt.Sprintf("I%03d_USD", i)
+	}
+	mps, err := client.NewMultiPriceServer(instrs...)
+	c.Assert(err, check.IsNil)
+
+	err = mps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		mps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+
+	// The stream may reconnect before Stop takes effect, but always for all instruments at once.
+	c.Assert(len(tr.Requests) > 0, check.Equals, true)
+	for _, req := range tr.Requests {
+		shardInstrs := strings.Split(req.URL.Query().Get("instruments"), ",")
+		c.Assert(shardInstrs, check.HasLen, len(instrs))
+	}
+}
+
+func (s *PriceServerSuite) TestPriceTickEqual(c *check.C) {
+	tick := oanda.PriceTick{Time: "1400000000000000", Bid: 1.1, Ask: 1.2}
+	dup := tick
+	c.Assert(tick.Equal(dup), check.Equals, true)
+	c.Assert(tick.EqualWithStatus(dup), check.Equals, true)
+
+	dup.Status = "halted"
+	c.Assert(tick.Equal(dup), check.Equals, true)
+	c.Assert(tick.EqualWithStatus(dup), check.Equals, false)
+
+	later := tick
+	later.Time = "1400000000000001"
+	c.Assert(tick.Equal(later), check.Equals, false)
+
+	moved := tick
+	moved.Ask = 1.21
+	c.Assert(tick.Equal(moved), check.Equals, false)
+}
+
+func (s *PriceServerSuite) TestSpreadTracker(c *check.C) {
+	st := oanda.NewSpreadTracker(3)
+	min, avg, max := st.SpreadStats("eur_usd")
+	c.Assert(math.IsNaN(min) && math.IsNaN(avg) && math.IsNaN(max), check.Equals, true)
+
+	for _, spread := range []float64{0.5, 0.1, 0.2, 0.3} {
+		st.Update("EUR_USD", oanda.PriceTick{Bid: 1.0, Ask: 1.0 + spread})
+	}
+	min, avg, max = st.SpreadStats("eur_usd")
+	c.Assert(math.Abs(min-0.1) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(avg-0.2) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(max-0.3) < 1e-9, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestPriceServerSpreadTracker(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.SpreadTracker = oanda.NewSpreadTracker(10)
+
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+
+	min, avg, max := ps.SpreadTracker.SpreadStats("EUR_USD")
+	c.Assert(math.Abs(min-0.1) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(avg-0.1) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(max-0.1) < 1e-9, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestVWAPTracker(c *check.C) {
+	_, err := oanda.NewVWAPTracker(0)
+	c.Assert(err, check.NotNil)
+
+	vt, err := oanda.NewVWAPTracker(10 * time.Second)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.IsNaN(vt.VWAP("EUR_USD")), check.Equals, true)
+
+	tick := func(sec int64, mid float64) oanda.PriceTick {
+		ts := oanda.Time(strconv.FormatInt((1400000000+sec)*1000000, 10))
+		return oanda.PriceTick{Time: ts, Bid: mid - 0.0001, Ask: mid + 0.0001}
+	}
+	vt.Update("EUR_USD", tick(0, 1.1))
+	vt.Update("EUR_USD", tick(5, 1.2))
+	c.Assert(math.Abs(vt.VWAP("eur/usd")-1.15) < 1e-9, check.Equals, true)
+
+	// The first tick drops out of the window.
+	vt.Update("eur_usd", tick(12, 1.3))
+	c.Assert(math.Abs(vt.VWAP("eur/usd")-1.25) < 1e-9, check.Equals, true)
+
+	// Ticks that are older than the window are ignored.
+	vt.Update("EUR_USD", tick(1, 2.0))
+	c.Assert(math.Abs(vt.VWAP("eur/usd")-1.25) < 1e-9, check.Equals, true)
+
+	c.Assert(math.IsNaN(vt.VWAP("GBP_USD")), check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestPriceServerVWAPTracker(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.VWAPTracker, err = oanda.NewVWAPTracker(time.Minute)
+	c.Assert(err, check.IsNil)
+
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(ps.VWAPTracker.VWAP("EUR_USD")-1.15) < 1e-9, check.Equals, true)
+}
+
+func (s *PriceServerSuite) TestWaitForPrice(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.09,"ask":1.0902}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000001000000","bid":1.1,"ask":1.1002}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000002000000","bid":1.11,"ask":1.1102}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tick, err := client.WaitForPrice(ctx, "eur/usd", func(pt oanda.PriceTick) bool {
+		return pt.Bid >= 1.10
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(tick.Bid, check.Equals, 1.1)
+	c.Assert(tick.Time, check.Equals, oanda.Time("1400000001000000"))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.WaitForPrice(ctx, "eur/usd", func(pt oanda.PriceTick) bool {
+		return pt.Bid >= 2.0
+	})
+	c.Assert(err, check.Equals, context.DeadlineExceeded)
+
+	n := len(tr.Requests)
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = client.WaitForPrice(ctx, "eur/usd", func(pt oanda.PriceTick) bool {
+		c.Error("unexpected tick")
+		return true
+	})
+	c.Assert(err, check.Equals, context.Canceled)
+	c.Assert(tr.Requests, check.HasLen, n)
+}
+
+func (s *PriceServerSuite) TestWaitForPriceCancelDuringBackoff(c *check.C) {
+	tr := stubTransport{StatusCode: 503}
+	client := newStubClient(c, &tr)
+
+	// The failed connection is retried after the one second delay of DefaultBackoff.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.WaitForPrice(ctx, "eur/usd", func(pt oanda.PriceTick) bool {
+		return true
+	})
+	c.Assert(err, check.Equals, context.DeadlineExceeded)
+	c.Assert(time.Since(start) < 500*time.Millisecond, check.Equals, true)
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
+
+func (s *PriceServerSuite) TestLastHeartbeat(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"heartbeat":{"time":"1400000000000000"}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(ps.LastHeartbeat().IsZero(), check.Equals, true)
+	c.Assert(ps.LastMessage().IsZero(), check.Equals, true)
+
+	start := time.Now()
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(ps.LastHeartbeat().Before(start), check.Equals, false)
+	c.Assert(ps.LastMessage().Before(ps.LastHeartbeat()), check.Equals, false)
+}
+
+func (s *PriceServerSuite) TestUnexpectedTickFunc(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"tick":{"instrument":"GBP_USD","time":"1400000000000000","bid":1.5,"ask":1.6}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+
+	mtx := sync.Mutex{}
+	unexpected := make(map[string]float64)
+	ps.UnexpectedTickFunc = func(instr string, pp oanda.PriceTick) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		unexpected[instr] = pp.Bid
+	}
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		c.Assert(instr, check.Equals, "EUR_USD")
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	c.Assert(unexpected, check.DeepEquals, map[string]float64{"GBP_USD": 1.5})
+}
+
+func (s *PriceServerSuite) TestAccountScopedPrices(c *check.C) {
+	for _, accountId := range []oanda.Id{0, 12345} {
+		tr := stubTransport{
+			StatusCode: 200,
+			Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+		}
+		client := newStubClient(c, &tr)
+		client.SelectAccount(accountId)
+
+		ps, err := client.NewPriceServer("eur_usd")
+		c.Assert(err, check.IsNil)
+		err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+			c.Assert(pp.Bid, check.Equals, 1.1)
+			ps.Stop()
+		})
+		c.Assert(err, check.IsNil)
+
+		c.Assert(len(tr.Requests) > 0, check.Equals, true)
+		q := tr.Requests[0].URL.Query()
+		c.Assert(q.Get("instruments"), check.Equals, "EUR_USD")
+		if accountId == 0 {
+			_, ok := q["accountId"]
+			c.Assert(ok, check.Equals, false)
+		} else {
+			c.Assert(q.Get("accountId"), check.Equals, "12345")
+		}
+	}
+}
+
+func (s *PriceServerSuite) TestCoalesceInterval(c *check.C) {
+	ticks := make([]string, 50)
+	for i := range ticks {
+		ticks[i] = fmt.Sprintf(
+			`{"tick":{"instrument":"EUR_USD","time":"%d","bid":1.1,"ask":1.2}}`, 1400000000000000+i)
+	}
+	tr := stubTransport{StatusCode: 200, Body: strings.Join(ticks, "\n")}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.CoalesceInterval = 250 * time.Millisecond
+
+	mtx := sync.Mutex{}
+	var received []time.Time
+	time.AfterFunc(time.Second, ps.Stop)
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		received = append(received, time.Now())
+	})
+	c.Assert(err, check.IsNil)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	c.Assert(len(received) >= 2 && len(received) <= 5, check.Equals, true,
+		check.Commentf("%d ticks received", len(received)))
+	for i := 1; i < len(received); i++ {
+		c.Assert(received[i].Sub(received[i-1]) >= 240*time.Millisecond, check.Equals, true)
+	}
+}
+
+func (s *PriceServerSuite) TestRequireOpenMarket(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_USD", "halted": true}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.RequireOpenMarket = true
+
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ps.Stop()
+	})
+	c.Assert(err, check.Equals, oanda.ErrMarketClosed)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].URL.Path, check.Equals, "/v1/instruments")
+}
+
+func (s *PriceServerSuite) TestCandleTracker(c *check.C) {
+	_, err := oanda.NewCandleTracker(oanda.M)
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	ct, err := oanda.NewCandleTracker(oanda.M5)
+	c.Assert(err, check.IsNil)
+	_, ok := ct.Candle("EUR_USD")
+	c.Assert(ok, check.Equals, false)
+
+	tick := func(sec int64, mid float64) oanda.PriceTick {
+		return oanda.PriceTick{
+			Time: oanda.Time(strconv.FormatInt((1400000100+sec)*1000000, 10)),
+			Bid:  mid - 0.0001,
+			Ask:  mid + 0.0001,
+		}
+	}
+
+	// 1400000100 is the start of an M5 candle.
+	ct.Update("EUR_USD", tick(10, 1.3))
+	ct.Update("EUR_USD", tick(20, 1.5))
+	ct.Update("EUR_USD", tick(30, 1.2))
+	ct.Update("EUR_USD", tick(40, 1.4))
+	candle, ok := ct.Candle("eur_usd")
+	c.Assert(ok, check.Equals, true)
+	c.Assert(candle.Time.Time().Unix(), check.Equals, int64(1400000100))
+	c.Assert(math.Abs(candle.OpenMid-1.3) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(candle.HighMid-1.5) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(candle.LowMid-1.2) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(candle.CloseMid-1.4) < 1e-9, check.Equals, true)
+	c.Assert(candle.Volume, check.Equals, 4)
+
+	ct.Update("EUR_USD", tick(300, 1.6))
+	ct.Update("EUR_USD", tick(50, 1.0))
+	candle, _ = ct.Candle("EUR_USD")
+	c.Assert(candle.Time.Time().Unix(), check.Equals, int64(1400000400))
+	c.Assert(math.Abs(candle.LowMid-1.6) < 1e-9, check.Equals, true)
+	c.Assert(candle.Volume, check.Equals, 1)
+}
+
+func (s *PriceServerSuite) TestPriceServerCandleTracker(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+	ps.CandleTracker, err = oanda.NewCandleTracker(oanda.M5)
+	c.Assert(err, check.IsNil)
+
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		candle, ok := ps.CandleTracker.Candle(instr)
+		c.Assert(ok, check.Equals, true)
+		c.Assert(math.Abs(candle.HighMid-1.15) < 1e-9, check.Equals, true)
+		ps.Stop()
+	})
+	c.Assert(err, check.IsNil)
+}
+
+func (s *PriceServerSuite) TestPriceServerCompletedCandles(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"tick":{"instrument":"EUR_USD","time":"1400000000000000","bid":1.1,"ask":1.2}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000030000000","bid":1.3,"ask":1.4}}
+			{"tick":{"instrument":"EUR_USD","time":"1400000070000000","bid":1.0,"ask":1.1}}`,
+	}
+	client := newStubClient(c, &tr)
+
+	ps, err := client.NewPriceServer("eur_usd")
+	c.Assert(err, check.IsNil)
+
+	candleC := make(chan oanda.MidpointCandle, 10)
+	for _, g := range []oanda.Granularity{oanda.M1, oanda.M5} {
+		ct, err := oanda.NewCandleTracker(g)
+		c.Assert(err, check.IsNil)
+		ct.CompleteFunc = func(instr string, candle oanda.MidpointCandle) {
+			c.Check(instr, check.Equals, "EUR_USD")
+			candleC <- candle
+		}
+		ps.CandleTrackers = append(ps.CandleTrackers, ct)
+	}
+
+	ticks := 0
+	err = ps.ConnectAndHandle(func(instr string, pp oanda.PriceTick) {
+		ticks++
+		if ticks == 3 {
+			c.Assert(candleC, check.HasLen, 1)
+			ps.Stop()
+		}
+	})
+	c.Assert(err, check.IsNil)
+
+	candle := <-candleC
+	c.Assert(candle.Complete, check.Equals, true)
+	c.Assert(candle.Time.Time().Unix(), check.Equals, int64(1399999980))
+	c.Assert(math.Abs(candle.OpenMid-1.15) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(candle.CloseMid-1.35) < 1e-9, check.Equals, true)
+	c.Assert(candle.Volume, check.Equals, 2)
+}
+
+func (s *PriceServerSuite) TestCheckTriangle(c *check.C) {
+	tr := stubTransport{
+		Bodies: map[string]string{
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_JPY", "pip": "0.01"}]}`,
+			"/v1/prices": `{"prices": [
+				{"instrument": "EUR_USD", "time": "1400000000000000", "bid": 1.0999, "ask": 1.1001},
+				{"instrument": "USD_JPY", "time": "1400000000000000", "bid": 119.99, "ask": 120.01},
+				{"instrument": "EUR_JPY", "time": "1400000000000000", "bid": 132.04, "ask": 132.06}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	t, err := client.CheckTriangle("usd/jpy", "eur_usd", "eurjpy")
+	c.Assert(err, check.IsNil)
+	c.Log(t)
+	c.Assert(t.Cross, check.Equals, "EUR_JPY")
+	c.Assert(t.Legs, check.Equals, [2]string{"EUR_USD", "USD_JPY"})
+	c.Assert(math.Abs(t.Implied-132) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(t.Actual-132.05) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(t.DiscrepancyPips-5) < 1e-6, check.Equals, true)
+
+	prices := 0
+	for _, req := range tr.Requests {
+		if req.URL.Path == "/v1/prices" {
+			prices++
+			c.Assert(req.URL.Query().Get("instruments"), check.Equals, "EUR_USD,USD_JPY,EUR_JPY")
+		}
+	}
+	c.Assert(prices, check.Equals, 1)
+
+	_, err = client.CheckTriangle("EUR_USD", "GBP_JPY", "EUR_JPY")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	_, err = client.CheckTriangle("EUR_USD", "EUR_GBP", "EUR_JPY")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+}
+
+func (s *PriceServerSuite) TestPollPricesWithoutAccount(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"prices": [
+		{"instrument": "EUR_USD", "time": "1400000000000000", "bid": 1.1, "ask": 1.2}]}`}
+	client := newStubClient(c, &tr)
+
+	prices, err := client.PollPrices("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(prices["EUR_USD"].Bid, check.Equals, 1.1)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	_, ok := tr.Requests[0].URL.Query()["accountId"]
+	c.Assert(ok, check.Equals, false)
+}
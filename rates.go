@@ -15,11 +15,18 @@
 package oanda
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/santegoeds/oanda/analytics"
 )
 
 type InterestRate struct {
@@ -31,6 +38,10 @@ func (ir InterestRate) String() string {
 	return fmt.Sprintf("InterestRate{Bid: %v, Ask: %v}", ir.Bid, ir.Ask)
 }
 
+// InstrumentInfo holds the details of an instrument.  An InstrumentInfo can be cached as JSON:
+// encoding/json writes the currencies of InterestRate in sorted order so that the same
+// InstrumentInfo always encodes to the same document, and the document decodes to an equal
+// InstrumentInfo.
 type InstrumentInfo struct {
 	DisplayName     string                  `json:"displayName"`
 	Pip             float64                 `json:"pip,string"`
@@ -60,6 +71,49 @@ func (ii InstrumentInfo) String() string {
 		ii.MinTrailingStop, ii.MarginRate, ii.Halted, ii.InterestRate)
 }
 
+// Decimals returns the number of decimals with which Oanda quotes prices for the instrument.  The
+// number is derived from Precision or, when Precision is not known, from Pip assuming prices are
+// quoted in fractional pips.  Decimals returns -1 if neither is known.
+func (ii InstrumentInfo) Decimals() int {
+	tick := ii.Precision
+	if tick <= 0 && ii.Pip > 0 {
+		tick = ii.Pip / 10
+	}
+	if tick <= 0 {
+		return -1
+	}
+	decimals := int(math.Floor(-math.Log10(tick) + 0.5))
+	if decimals < 0 {
+		decimals = 0
+	}
+	return decimals
+}
+
+// FormatPrice formats price with the number of decimals that Oanda uses to quote the instrument.
+// The price is formatted with the shortest representation if neither Precision nor Pip are known.
+func (ii InstrumentInfo) FormatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', ii.Decimals(), 64)
+}
+
+// PriceToPips returns the number of pips in the price difference diff.  The result is rounded to a
+// millionth of a pip to remove floating point noise, so that a difference of 0.0003 for EUR_USD is
+// exactly 3 pips.  NaN is returned if Pip is not known.
+func (ii InstrumentInfo) PriceToPips(diff float64) float64 {
+	if ii.Pip <= 0 {
+		return math.NaN()
+	}
+	return math.Floor(diff/ii.Pip*1e6+0.5) / 1e6
+}
+
+// PipsToPrice returns the price difference that corresponds to pips.  NaN is returned if Pip is
+// not known.
+func (ii InstrumentInfo) PipsToPrice(pips float64) float64 {
+	if ii.Pip <= 0 {
+		return math.NaN()
+	}
+	return pips * ii.Pip
+}
+
 type InstrumentField string
 
 const (
@@ -74,11 +128,30 @@ const (
 	InterestRateField    InstrumentField = "interestRate"
 )
 
+// IsAccountSpecific returns true if the value of the field depends on the account for which it is
+// requested.
+func (f InstrumentField) IsAccountSpecific() bool {
+	return f == MarginRateField || f == InterestRateField
+}
+
 // Instruments returns instrument information.  Only the specified instruments are returned if instruments
 // is not nil.  If fields is not nil additional information fields is included.
 //
+// The values of MarginRateField and InterestRateField are specific to an account; an error is
+// returned if either is requested and no account is selected.
+//
 // See http://developer.oanda.com/docs/v1/rates/#get-an-instrument-list for further information.
 func (c *Client) Instruments(instruments []string, fields []InstrumentField) (map[string]InstrumentInfo, error) {
+	if c.accountId == 0 {
+		for _, f := range fields {
+			if f.IsAccountSpecific() {
+				return nil, &ValidationError{
+					Field:  "fields",
+					Reason: fmt.Sprintf("Field %s requires a selected account.", f),
+				}
+			}
+		}
+	}
 
 	u, err := url.Parse("/v1/instruments")
 	if err != nil {
@@ -87,7 +160,10 @@ func (c *Client) Instruments(instruments []string, fields []InstrumentField) (ma
 
 	q := u.Query()
 	if len(instruments) > 0 {
-		q.Set("instruments", strings.ToUpper(strings.Join(instruments, ",")))
+		if instruments, err = normalizeInstruments(instruments); err != nil {
+			return nil, err
+		}
+		q.Set("instruments", strings.Join(instruments, ","))
 	}
 	if len(fields) > 0 {
 		ss := make([]string, len(fields))
@@ -119,6 +195,168 @@ func (c *Client) Instruments(instruments []string, fields []InstrumentField) (ma
 	return info, nil
 }
 
+// instrumentNames returns the sorted names of all instruments that are available for trading.
+func (c *Client) instrumentNames() ([]string, error) {
+	info, err := c.Instruments(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(info))
+	for name := range info {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// InstrumentsByBase returns the available instruments grouped by their base currency.  The
+// instruments of every currency are sorted by name.
+func (c *Client) InstrumentsByBase() (map[string][]string, error) {
+	return c.groupInstruments(func(base, quote string) string { return base })
+}
+
+// InstrumentsByQuote returns the available instruments grouped by their quote currency.  The
+// instruments of every currency are sorted by name.
+func (c *Client) InstrumentsByQuote() (map[string][]string, error) {
+	return c.groupInstruments(func(base, quote string) string { return quote })
+}
+
+func (c *Client) groupInstruments(key func(base, quote string) string) (map[string][]string, error) {
+	names, err := c.instrumentNames()
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string][]string)
+	for _, name := range names {
+		k := key(SplitInstrument(name))
+		groups[k] = append(groups[k], name)
+	}
+	return groups, nil
+}
+
+// CurrenciesInvolving returns the sorted names of the available instruments that have currency
+// as either their base or their quote currency.
+func (c *Client) CurrenciesInvolving(currency string) ([]string, error) {
+	names, err := c.instrumentNames()
+	if err != nil {
+		return nil, err
+	}
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	var instruments []string
+	for _, name := range names {
+		if hasCurrency(name, currency) {
+			instruments = append(instruments, name)
+		}
+	}
+	return instruments, nil
+}
+
+// cachedInstrumentFields are the fields of the InstrumentInfo that is cached by the Client.
+var cachedInstrumentFields = []InstrumentField{
+	DisplayNameField,
+	PipField,
+	MaxTradeUnitsField,
+	PrecisionField,
+	MaxTrailingStopField,
+	MinTrailingStopField,
+	HaltedField,
+}
+
+// InstrumentInfo returns information about instrument.  The information is retrieved on first
+// use and cached by the Client.  The cached information of an instrument is discarded, and
+// retrieved again on next use, when a change in its halted status is observed on a price tick
+// or when an order is rejected because trading in the instrument is halted.
+//
+// The financing rates in InterestRate are included if an account is selected.
+func (c *Client) InstrumentInfo(instrument string) (InstrumentInfo, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return InstrumentInfo{}, err
+	}
+
+	c.instMtx.Lock()
+	info, ok := c.instCache[instrument]
+	c.instMtx.Unlock()
+	if ok {
+		return info, nil
+	}
+
+	fields := cachedInstrumentFields
+	if c.accountId != 0 {
+		fields = append([]InstrumentField{InterestRateField}, cachedInstrumentFields...)
+	}
+	instruments, err := c.Instruments([]string{instrument}, fields)
+	if err != nil {
+		return InstrumentInfo{}, err
+	}
+	info, ok = instruments[instrument]
+	if !ok {
+		return InstrumentInfo{}, fmt.Errorf("Unknown instrument %s", instrument)
+	}
+
+	c.instMtx.Lock()
+	defer c.instMtx.Unlock()
+	if c.instCache == nil {
+		c.instCache = make(map[string]InstrumentInfo)
+	}
+	c.instCache[instrument] = info
+	return info, nil
+}
+
+// FinancingRates returns the bid and ask financing rates of the base and quote currencies of
+// instrument, keyed by currency, for the selected account.  The rates are cached along with the
+// other InstrumentInfo; see Client.InstrumentInfo.
+func (c *Client) FinancingRates(instrument string) (map[string]InterestRate, error) {
+	if c.accountId == 0 {
+		return nil, &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	info, err := c.InstrumentInfo(instrument)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]InterestRate, len(info.InterestRate))
+	for ccy, rate := range info.InterestRate {
+		rates[ccy] = rate
+	}
+	return rates, nil
+}
+
+// IsMarketOpen returns true if trading in instrument is not halted.
+func (c *Client) IsMarketOpen(instrument string) (bool, error) {
+	info, err := c.InstrumentInfo(instrument)
+	if err != nil {
+		return false, err
+	}
+	return !info.Halted, nil
+}
+
+// InvalidateInstrument discards the cached information of instrument.
+func (c *Client) InvalidateInstrument(instrument string) {
+	c.instMtx.Lock()
+	defer c.instMtx.Unlock()
+	delete(c.instCache, instrumentKey(instrument))
+}
+
+// observeTickStatus invalidates the cached information of instrument if the halted status of a
+// tick differs from the cached status.
+func (c *Client) observeTickStatus(instrument, status string) {
+	halted := status == "halted"
+
+	c.instMtx.Lock()
+	defer c.instMtx.Unlock()
+	if info, ok := c.instCache[instrument]; ok && info.Halted != halted {
+		delete(c.instCache, instrument)
+	}
+}
+
+// observeApiError invalidates the cached information of instrument if err reports that trading
+// in instrument is halted.
+func (c *Client) observeApiError(instrument string, err error) {
+	if apiErr, ok := err.(*ApiError); ok && apiErr.IsMarketHalted() {
+		c.InvalidateInstrument(instrument)
+	}
+}
+
 type (
 	// Granularity determines the interval at which historic instrument prices are converted into candles.
 	Granularity string
@@ -148,6 +386,253 @@ const (
 	M   Granularity = "M"
 )
 
+// AllGranularities lists the granularities that Oanda supports from the shortest to the longest.
+var AllGranularities = []Granularity{
+	S5, S10, S15, S30, M1, M2, M3, M5, M10, M15, M30, H1, H2, H3, H4, H6, H8, H12, D, W, M,
+}
+
+// IsValid returns true if g is one of the granularities that Oanda supports.
+func (g Granularity) IsValid() bool {
+	for _, valid := range AllGranularities {
+		if g == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// Duration returns the time period that is covered by a candle of granularity g.  Zero is
+// returned for monthly candles as the length of a month varies.
+func (g Granularity) Duration() time.Duration {
+	switch g {
+	case S5:
+		return 5 * time.Second
+	case S10:
+		return 10 * time.Second
+	case S15:
+		return 15 * time.Second
+	case S30:
+		return 30 * time.Second
+	case M1:
+		return time.Minute
+	case M2:
+		return 2 * time.Minute
+	case M3:
+		return 3 * time.Minute
+	case M5:
+		return 5 * time.Minute
+	case M10:
+		return 10 * time.Minute
+	case M15:
+		return 15 * time.Minute
+	case M30:
+		return 30 * time.Minute
+	case H1:
+		return time.Hour
+	case H2:
+		return 2 * time.Hour
+	case H3:
+		return 3 * time.Hour
+	case H4:
+		return 4 * time.Hour
+	case H6:
+		return 6 * time.Hour
+	case H8:
+		return 8 * time.Hour
+	case H12:
+		return 12 * time.Hour
+	case D:
+		return 24 * time.Hour
+	case W:
+		return 7 * 24 * time.Hour
+	}
+	return 0
+}
+
+// NextClose returns the first time after now at which a candle of granularity g closes.  Candles
+// of hourly or longer granularity are aligned to the hour alignment in loc, similar to the
+// DailyAlignment and AlignmentTimezone arguments of PollMidpointCandles, and shorter candles are
+// aligned to whole multiples of their duration within that day.  Weekly candles close on Friday and
+// monthly candles on the first day of the month.  A nil loc is treated as UTC.  The zero time is
+// returned if g is not a valid granularity.
+func (g Granularity) NextClose(now time.Time, alignment int, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now = now.In(loc)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), alignment, 0, 0, 0, loc)
+	if dayStart.After(now) {
+		dayStart = dayStart.AddDate(0, 0, -1)
+	}
+
+	switch g {
+	case D:
+		return dayStart.AddDate(0, 0, 1)
+	case W:
+		next := dayStart.AddDate(0, 0, int(time.Friday-dayStart.Weekday()+7)%7)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 7)
+		}
+		return next
+	case M:
+		next := time.Date(now.Year(), now.Month(), 1, alignment, 0, 0, 0, loc)
+		if !next.After(now) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next
+	}
+
+	d := g.Duration()
+	if d == 0 {
+		return time.Time{}
+	}
+	next := dayStart.Add((now.Sub(dayStart)/d + 1) * d)
+	// The last candle of the day is cut short when the day is shortened by daylight saving time.
+	if dayEnd := dayStart.AddDate(0, 0, 1); next.After(dayEnd) {
+		next = dayEnd
+	}
+	return next
+}
+
+// CandleGaps verifies that the candle start times in times are evenly spaced for granularity and
+// returns the indices i for which times[i] does not immediately follow times[i-1].  Gaps that fall
+// within the weekend closure of the market, between Friday 21:00 UTC and Sunday 23:00 UTC, are
+// not reported.  Daily and weekly candles may shift by an hour when daylight saving time starts
+// or ends.
+func CandleGaps(granularity Granularity, times []time.Time) []int {
+	var gaps []int
+	for i := 1; i < len(times); i++ {
+		if !isCandleSuccessor(granularity, times[i-1].UTC(), times[i].UTC()) {
+			gaps = append(gaps, i)
+		}
+	}
+	return gaps
+}
+
+// isCandleSuccessor returns true if a candle of granularity that starts at cur is the candle that
+// follows the candle that starts at prev.
+func isCandleSuccessor(granularity Granularity, prev, cur time.Time) bool {
+	if granularity == M {
+		py, pm, _ := prev.Date()
+		cy, cm, _ := cur.Date()
+		return cy*12+int(cm) == py*12+int(pm)+1
+	}
+
+	d := granularity.Duration()
+	diff := cur.Sub(prev)
+	tolerance := time.Duration(0)
+	if d >= 24*time.Hour {
+		tolerance = time.Hour
+	}
+	switch {
+	case diff <= 0:
+		return false
+	case diff >= d-tolerance && diff <= d+tolerance:
+		return true
+	}
+
+	// Candles are missing between prev and cur which is expected only if the market was closed.
+	missing := prev.Add(d)
+	weekendStart, ok := weekendClosure(missing)
+	return ok && !cur.After(weekendStart.Add(weekendDuration))
+}
+
+const weekendDuration = 50 * time.Hour
+
+// weekendClosure returns the start of the weekend closure of the market, Friday 21:00 UTC, that
+// contains t.  The boolean result is false if t does not fall within a weekend closure.
+func weekendClosure(t time.Time) (time.Time, bool) {
+	y, m, d := t.Date()
+	friday := time.Date(y, m, d, 21, 0, 0, 0, time.UTC)
+	friday = friday.AddDate(0, 0, -((int(t.Weekday()) - int(time.Friday) + 7) % 7))
+	if t.Before(friday) || !t.Before(friday.Add(weekendDuration)) {
+		return time.Time{}, false
+	}
+	return friday, true
+}
+
+// CandleReturn is the return from the close of a candle to the close of the next candle.
+type CandleReturn struct {
+	// Time is the start time of the candle at whose close the return is measured.
+	Time   time.Time
+	Simple float64
+	Log    float64
+	// SpansWeekend is true if the return includes the weekend closure of the market.
+	SpansWeekend bool
+}
+
+// CandleReturns is a series of returns as computed by ComputeCandleReturns.
+type CandleReturns []CandleReturn
+
+// ComputeCandleReturns returns the returns between the consecutive closes of candles of
+// granularity that start at times.  Returns from the last candle before to the first candle after
+// the weekend closure are flagged with SpansWeekend, except for weekly and monthly candles which
+// always include a weekend.
+func ComputeCandleReturns(granularity Granularity, times []time.Time,
+	closes []float64) CandleReturns {
+
+	if len(times) < 2 || len(closes) != len(times) {
+		return nil
+	}
+	returns := make(CandleReturns, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		returns[i-1] = CandleReturn{
+			Time:         times[i],
+			Simple:       closes[i]/closes[i-1] - 1,
+			Log:          math.Log(closes[i] / closes[i-1]),
+			SpansWeekend: spansWeekend(granularity, times[i-1].UTC(), times[i].UTC()),
+		}
+	}
+	return returns
+}
+
+// ExcludeWeekends returns the returns that do not span the weekend closure of the market.
+func (cr CandleReturns) ExcludeWeekends() CandleReturns {
+	excl := make(CandleReturns, 0, len(cr))
+	for _, r := range cr {
+		if !r.SpansWeekend {
+			excl = append(excl, r)
+		}
+	}
+	return excl
+}
+
+// Simple returns the simple returns of the series.
+func (cr CandleReturns) Simple() []float64 {
+	values := make([]float64, len(cr))
+	for i, r := range cr {
+		values[i] = r.Simple
+	}
+	return values
+}
+
+// Log returns the log returns of the series.
+func (cr CandleReturns) Log() []float64 {
+	values := make([]float64, len(cr))
+	for i, r := range cr {
+		values[i] = r.Log
+	}
+	return values
+}
+
+// spansWeekend returns true if the candle of granularity that starts at cur follows the candle
+// that starts at prev across the weekend closure of the market.
+func spansWeekend(granularity Granularity, prev, cur time.Time) bool {
+	d := granularity.Duration()
+	if d == 0 || d >= 7*24*time.Hour {
+		return false
+	}
+	tolerance := time.Duration(0)
+	if d >= 24*time.Hour {
+		tolerance = time.Hour
+	}
+	if cur.Sub(prev) <= d+tolerance {
+		return false
+	}
+	_, ok := weekendClosure(prev.Add(d))
+	return ok
+}
+
 // CandlesArg implements optional arguments for MidpointCandles and BidAskCandles.
 type CandlesArg interface {
 	applyCandlesArg(url.Values)
@@ -232,6 +717,43 @@ func (c MidpointCandles) String() string {
 		c.Instrument, c.Granularity, c.Candles)
 }
 
+// Gaps returns the indices of the candles that do not immediately follow the previous candle.
+// See CandleGaps for further information.
+func (c MidpointCandles) Gaps() []int {
+	times := make([]time.Time, len(c.Candles))
+	for i, candle := range c.Candles {
+		times[i] = candle.Time.Time()
+	}
+	return CandleGaps(c.Granularity, times)
+}
+
+// IsStale returns true if the most recent candle started more than maxAge ago, such as during a
+// weekend or when trading in the instrument is halted, or if there are no candles.
+func (c MidpointCandles) IsStale(maxAge time.Duration) bool {
+	return c.IsStaleAt(time.Now(), maxAge)
+}
+
+// IsStaleAt is like IsStale but measures the age of the most recent candle at now, such as the
+// time of the Clock that was given to Client.SetClock.
+func (c MidpointCandles) IsStaleAt(now time.Time, maxAge time.Duration) bool {
+	if len(c.Candles) == 0 {
+		return true
+	}
+	return isStale(c.Candles[len(c.Candles)-1].Time, now, maxAge)
+}
+
+// Returns returns the returns between the consecutive CloseMid prices of the candles.  See
+// ComputeCandleReturns for further information.
+func (c MidpointCandles) Returns() CandleReturns {
+	times := make([]time.Time, len(c.Candles))
+	closes := make([]float64, len(c.Candles))
+	for i, candle := range c.Candles {
+		times[i] = candle.Time.Time()
+		closes[i] = candle.CloseMid
+	}
+	return ComputeCandleReturns(c.Granularity, times, closes)
+}
+
 // BidAskCandles represents Bid and Ask instrument history with a specific granularity.
 type BidAskCandles struct {
 	Instrument  string         `json:"instrument"`
@@ -239,11 +761,50 @@ type BidAskCandles struct {
 	Candles     []BidAskCandle `json:"candles"`
 }
 
+// IsStale returns true if the most recent candle started more than maxAge ago, or if there are
+// no candles.  See MidpointCandles.IsStale.
+func (c BidAskCandles) IsStale(maxAge time.Duration) bool {
+	return c.IsStaleAt(time.Now(), maxAge)
+}
+
+// IsStaleAt is like IsStale but measures the age of the most recent candle at now.
+func (c BidAskCandles) IsStaleAt(now time.Time, maxAge time.Duration) bool {
+	if len(c.Candles) == 0 {
+		return true
+	}
+	return isStale(c.Candles[len(c.Candles)-1].Time, now, maxAge)
+}
+
 func (c BidAskCandles) String() string {
 	return fmt.Sprintf("BidAskCandles{Instrument: %s, Granularity: %v, Candles: %v}", c.Instrument,
 		c.Granularity, c.Candles)
 }
 
+// Gaps returns the indices of the candles that do not immediately follow the previous candle.
+// See CandleGaps for further information.
+func (c BidAskCandles) Gaps() []int {
+	times := make([]time.Time, len(c.Candles))
+	for i, candle := range c.Candles {
+		times[i] = candle.Time.Time()
+	}
+	return CandleGaps(c.Granularity, times)
+}
+
+// Returns returns the returns between the consecutive mid prices of CloseBid and CloseAsk of the
+// candles.  See ComputeCandleReturns for further information.
+func (c BidAskCandles) Returns() CandleReturns {
+	times := make([]time.Time, len(c.Candles))
+	closes := make([]float64, len(c.Candles))
+	for i, candle := range c.Candles {
+		times[i] = candle.Time.Time()
+		closes[i] = (candle.CloseBid + candle.CloseAsk) / 2
+	}
+	return ComputeCandleReturns(c.Granularity, times, closes)
+}
+
+// maxCandlesCount is the maximum number of candles that Oanda returns for a single request.
+const maxCandlesCount = 5000
+
 // PollMidpointCandles returns historical midpoint prices for an instrument.
 func (c *Client) PollMidpointCandles(instrument string, granularity Granularity,
 	args ...CandlesArg) (*MidpointCandles, error) {
@@ -259,6 +820,60 @@ func (c *Client) PollMidpointCandles(instrument string, granularity Granularity,
 	return &candles, nil
 }
 
+// PollMidpointCandlesAround returns the historical midpoint prices for an instrument in the window
+// from t-window until t+window, such as around the time of a CalendarEvent.  Optional arguments
+// args are applied to the request as well, except that Count can not be combined with the start
+// and end time of the window.
+func (c *Client) PollMidpointCandlesAround(instrument string, granularity Granularity, t time.Time,
+	window time.Duration, args ...CandlesArg) (*MidpointCandles, error) {
+
+	if window <= 0 {
+		return nil, &ValidationError{
+			Field:  "window",
+			Reason: fmt.Sprintf("A positive window is required instead of %v.", window),
+		}
+	}
+	args = append([]CandlesArg{StartTime(t.Add(-window)), EndTime(t.Add(window))}, args...)
+	return c.PollMidpointCandles(instrument, granularity, args...)
+}
+
+// PollLastCompleteCandles returns the n most recent complete midpoint candles of an instrument.
+// The forming candle, which Oanda includes as the last candle, is dropped.  Fewer than n candles
+// are returned only if Oanda has fewer complete candles for the instrument.
+func (c *Client) PollLastCompleteCandles(instrument string, granularity Granularity,
+	n int) (*MidpointCandles, error) {
+
+	if n < 1 || n >= maxCandlesCount {
+		return nil, &ValidationError{
+			Field:  "n",
+			Reason: fmt.Sprintf("n must be between 1 and %d instead of %d.", maxCandlesCount-1, n),
+		}
+	}
+	candles, err := c.PollMidpointCandles(instrument, granularity, Count(n+1))
+	if err != nil {
+		return nil, err
+	}
+	complete := make([]MidpointCandle, 0, len(candles.Candles))
+	for _, candle := range candles.Candles {
+		if candle.Complete {
+			complete = append(complete, candle)
+		}
+	}
+	if len(complete) > n {
+		complete = complete[len(complete)-n:]
+	}
+	candles.Candles = complete
+	if d := granularity.Duration(); d > 0 && len(complete) > 0 {
+		// The most recent complete candle ended at most one candle ago.
+		last := complete[len(complete)-1].Time.Time()
+		if age := c.clock().Now().Sub(last); age > 2*d {
+			log.Printf("latest complete %s candle of %s is stale: it started %v ago",
+				granularity, candles.Instrument, age)
+		}
+	}
+	return candles, nil
+}
+
 // PollBidAskCandles returns historical bid- and ask prices for an instrument.
 func (c *Client) PollBidAskCandles(instrument string, granularity Granularity,
 	args ...CandlesArg) (*BidAskCandles, error) {
@@ -274,12 +889,290 @@ func (c *Client) PollBidAskCandles(instrument string, granularity Granularity,
 	return &candles, nil
 }
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CloseTable
+
+// A CloseTable holds the closing midpoint prices of several instruments aligned on the start
+// times of their candles.
+type CloseTable struct {
+	Instruments []string
+	// Times are the start times of the candles in chronological order.
+	Times []time.Time
+	// Closes holds a row per time with a column per instrument.  Closes[i][j] is the close of
+	// Instruments[j] in the candle that started at Times[i], or NaN if the instrument has no
+	// candle at that time.
+	Closes [][]float64
+}
+
+// Column returns the closes of instrument, or nil if the table has no column for instrument.
+func (ct CloseTable) Column(instrument string) []float64 {
+	instrument = instrumentKey(instrument)
+	for j, instr := range ct.Instruments {
+		if instr != instrument {
+			continue
+		}
+		column := make([]float64, len(ct.Closes))
+		for i, row := range ct.Closes {
+			column[i] = row[j]
+		}
+		return column
+	}
+	return nil
+}
+
+// PollCloseTable polls the midpoint candles of instruments with granularity and returns the
+// closes of the complete candles as a CloseTable.  Optional arguments args are applied to the
+// request for every instrument.
+func (c *Client) PollCloseTable(granularity Granularity, instruments []string,
+	args ...CandlesArg) (*CloseTable, error) {
+
+	instruments, err := normalizeInstruments(instruments)
+	if err != nil {
+		return nil, err
+	}
+
+	closes := make([]map[time.Time]float64, len(instruments))
+	var times []time.Time
+	seen := make(map[time.Time]bool)
+	for j, instr := range instruments {
+		candles, err := c.PollMidpointCandles(instr, granularity, args...)
+		if err != nil {
+			return nil, err
+		}
+		closes[j] = make(map[time.Time]float64, len(candles.Candles))
+		for _, candle := range candles.Candles {
+			if !candle.Complete {
+				continue
+			}
+			t := candle.Time.Time().UTC()
+			closes[j][t] = candle.CloseMid
+			if !seen[t] {
+				seen[t] = true
+				times = append(times, t)
+			}
+		}
+	}
+	sort.Sort(timeSlice(times))
+
+	ct := CloseTable{
+		Instruments: instruments,
+		Times:       times,
+		Closes:      make([][]float64, len(times)),
+	}
+	for i, t := range times {
+		row := make([]float64, len(instruments))
+		for j := range instruments {
+			if v, ok := closes[j][t]; ok {
+				row[j] = v
+			} else {
+				row[j] = math.NaN()
+			}
+		}
+		ct.Closes[i] = row
+	}
+	return &ct, nil
+}
+
+// timeSlice implements sort.Interface to sort times in chronological order.
+type timeSlice []time.Time
+
+func (ts timeSlice) Len() int           { return len(ts) }
+func (ts timeSlice) Less(i, j int) bool { return ts[i].Before(ts[j]) }
+func (ts timeSlice) Swap(i, j int)      { ts[i], ts[j] = ts[j], ts[i] }
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CandleStreamer
+
+// defaultCandleDelay is the default time after the close of a candle at which a CandleStreamer
+// polls for the candle.
+const defaultCandleDelay = time.Second
+
+type CandleHandlerFunc func(instrument string, candle MidpointCandle)
+
+// A CandleStreamer polls for the midpoint candles of an instrument at the close of every candle
+// and delivers each candle once it is complete.
+type CandleStreamer struct {
+	// Delay is the time after the close of a candle at which the CandleStreamer polls for the
+	// completed candle.
+	Delay time.Duration
+
+	c           *Client
+	instrument  string
+	granularity Granularity
+	mtx         sync.Mutex
+	stopC       chan struct{}
+}
+
+// NewCandleStreamer returns a CandleStreamer for the candles of instrument with granularity.
+// Monthly candles are not supported.
+func (c *Client) NewCandleStreamer(instrument string, granularity Granularity) (*CandleStreamer, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
+	if granularity.Duration() == 0 {
+		return nil, &ValidationError{
+			Field:  "granularity",
+			Reason: fmt.Sprintf("Granularity %s is not supported.", granularity),
+		}
+	}
+	cs := CandleStreamer{
+		Delay:       defaultCandleDelay,
+		c:           c,
+		instrument:  instrument,
+		granularity: granularity,
+	}
+	return &cs, nil
+}
+
+// Run polls for candles and invokes handleFn for every candle that completes after Run is
+// called.  Candles are polled at the close of every candle, aligned on the candle start times
+// that Oanda returns.  Run returns when Stop is called or when polling fails.
+func (cs *CandleStreamer) Run(handleFn CandleHandlerFunc) error {
+	cs.mtx.Lock()
+	if cs.stopC != nil {
+		cs.mtx.Unlock()
+		return errors.New("candle streamer is already running")
+	}
+	stopC := make(chan struct{})
+	cs.stopC = stopC
+	cs.mtx.Unlock()
+
+	clock := cs.c.clock()
+	d := cs.granularity.Duration()
+	var last time.Time
+	for {
+		candles, err := cs.c.PollMidpointCandles(cs.instrument, cs.granularity, Count(5))
+		if err != nil {
+			cs.Stop()
+			return err
+		}
+
+		// The candles that completed before the first poll only establish the starting point.
+		first := last.IsZero()
+		for _, candle := range candles.Candles {
+			t := candle.Time.Time()
+			if !candle.Complete || !t.After(last) {
+				continue
+			}
+			if !first {
+				select {
+				case <-stopC:
+					return nil
+				default:
+				}
+				handleFn(cs.instrument, candle)
+			}
+			last = t
+		}
+
+		// The next candle completes at the first multiple of d after the start of the last
+		// completed candle that lies in the future.
+		now := clock.Now()
+		next := now.Truncate(d).Add(d)
+		if !last.IsZero() {
+			next = last.Add(now.Sub(last)/d*d + d)
+		}
+
+		timerC, stopTimer := newTimer(clock, next.Sub(now)+cs.Delay)
+		select {
+		case <-stopC:
+			stopTimer()
+			return nil
+		case <-timerC:
+		}
+	}
+}
+
+// Stop terminates the CandleStreamer.
+func (cs *CandleStreamer) Stop() {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	if cs.stopC != nil {
+		close(cs.stopC)
+		cs.stopC = nil
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// CandleWindow
+
+// IncompleteCandleMode determines how a CandleWindow handles a candle that is still forming.
+type IncompleteCandleMode int
+
+const (
+	// ExcludeIncomplete ignores incomplete candles so that the window only changes when a candle
+	// completes.
+	ExcludeIncomplete IncompleteCandleMode = iota
+
+	// ReplaceIncomplete adds the close of a forming candle to the window and replaces, rather than
+	// appends, that value as the candle updates and when it completes.
+	ReplaceIncomplete
+)
+
+// A CandleWindow feeds the closes of midpoint candles into an analytics.Window for use by
+// indicators.  Candles must be added in chronological order; a candle that is older than the most
+// recent candle in the window is ignored.
+type CandleWindow struct {
+	mode     IncompleteCandleMode
+	w        *analytics.Window
+	lastTime Time
+	forming  bool
+}
+
+// NewCandleWindow returns a CandleWindow that holds the closes of up to capacity candles and that
+// handles incomplete candles according to mode.
+func NewCandleWindow(capacity int, mode IncompleteCandleMode) *CandleWindow {
+	return &CandleWindow{
+		mode: mode,
+		w:    analytics.NewWindow(capacity),
+	}
+}
+
+// Update adds the closes of candles to the window.  An update of the candle that was added most
+// recently replaces its close if that candle was incomplete and is otherwise ignored.
+func (cw *CandleWindow) Update(candles ...MidpointCandle) {
+	for _, candle := range candles {
+		if !candle.Complete && cw.mode == ExcludeIncomplete {
+			continue
+		}
+		if cw.w.Len() > 0 {
+			t, last := candle.Time.UnixMicro(), cw.lastTime.UnixMicro()
+			if t < last || t == last && !cw.forming {
+				continue
+			}
+			if t == last {
+				cw.w.Replace(candle.CloseMid)
+				cw.forming = !candle.Complete
+				continue
+			}
+		}
+		cw.w.Push(candle.CloseMid)
+		cw.lastTime = candle.Time
+		cw.forming = !candle.Complete
+	}
+}
+
+// Window returns the window of closes, most recent close first.  The window is updated in place
+// by subsequent calls to Update.
+func (cw *CandleWindow) Window() *analytics.Window {
+	return cw.w
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Private
 
+func isStale(t Time, now time.Time, maxAge time.Duration) bool {
+	return now.Sub(t.Time()) > maxAge
+}
+
 func (c *Client) newCandlesURL(instrument string, granularity Granularity, candleFormat string,
 	args ...CandlesArg) (*url.URL, error) {
 
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 	u, err := url.Parse("/v1/candles")
 	if err != nil {
 		return nil, err
@@ -288,10 +1181,17 @@ func (c *Client) newCandlesURL(instrument string, granularity Granularity, candl
 	q := u.Query()
 	q.Set("candleFormat", candleFormat)
 	q.Set("granularity", string(granularity))
-	q.Set("instrument", strings.ToUpper(instrument))
+	q.Set("instrument", instrument)
 	for _, arg := range args {
 		arg.applyCandlesArg(q)
 	}
+	// Oanda derives the missing bound of the period from count, so all three can not be given.
+	if q.Get("count") != "" && q.Get("start") != "" && q.Get("end") != "" {
+		return nil, &ValidationError{
+			Field:  "count",
+			Reason: "Count can not be combined with both StartTime and EndTime.",
+		}
+	}
 	u.RawQuery = q.Encode()
 
 	return u, err
@@ -15,7 +15,13 @@
 package oanda_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -33,6 +39,217 @@ func (ts *TestRatesSuite) SetUpSuite(c *check.C) {
 	ts.SetUpAccount(c)
 }
 
+type RatesSuite struct{}
+
+var _ = check.Suite(&RatesSuite{})
+
+func (s *RatesSuite) TestInstrumentsAccountSpecificFields(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"instruments": []}`}
+	client := newStubClient(c, &tr)
+
+	_, err := client.Instruments(nil, []oanda.InstrumentField{oanda.MarginRateField})
+	c.Assert(err, check.NotNil)
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	client.SelectAccount(1)
+	_, err = client.Instruments(nil, []oanda.InstrumentField{oanda.MarginRateField})
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].URL.Query().Get("accountId"), check.Equals, "1")
+}
+
+func (s *RatesSuite) TestIsMarketOpenAfterHalt(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_USD", "halted": false}]}`,
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "time": "1400000000000000",
+				"bid": 1.1, "ask": 1.2, "status": "halted"}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	open, err := client.IsMarketOpen("eur_usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(open, check.Equals, true)
+	open, err = client.IsMarketOpen("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(open, check.Equals, true)
+	c.Assert(tr.Requests, check.HasLen, 1)
+
+	tr.Bodies["/v1/instruments"] = `{"instruments": [{"instrument": "EUR_USD", "halted": true}]}`
+	_, err = client.PollPrices("eur_usd")
+	c.Assert(err, check.IsNil)
+
+	open, err = client.IsMarketOpen("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(open, check.Equals, false)
+	c.Assert(tr.Requests, check.HasLen, 3)
+}
+
+func (s *RatesSuite) TestFinancingRates(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_USD", "interestRate": {
+				"EUR": {"bid": -0.004, "ask": 0.001}, "USD": {"bid": 0.01, "ask": 0.015}}}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.FinancingRates("EUR_USD")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	client.SelectAccount(1)
+	rates, err := client.FinancingRates("eur_usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(rates, check.DeepEquals, map[string]oanda.InterestRate{
+		"EUR": {Bid: -0.004, Ask: 0.001},
+		"USD": {Bid: 0.01, Ask: 0.015},
+	})
+	c.Assert(tr.Requests, check.HasLen, 1)
+	q := tr.Requests[0].URL.Query()
+	c.Assert(strings.Contains(q.Get("fields"), "interestRate"), check.Equals, true)
+	c.Assert(q.Get("accountId"), check.Equals, "1")
+
+	_, err = client.FinancingRates("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
+
+func (s *RatesSuite) TestMarketHaltedApiError(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body:       `{"instruments": [{"instrument": "EUR_USD", "halted": false}]}`,
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	open, err := client.IsMarketOpen("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(open, check.Equals, true)
+
+	tr.StatusCode = 400
+	tr.Body = `{"message": "Instrument trading halted"}`
+	_, err = client.NewTrade(oanda.Buy, 1, "eur_usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.ApiError{})
+	c.Assert(err.(*oanda.ApiError).IsMarketHalted(), check.Equals, true)
+
+	tr.StatusCode = 200
+	tr.Body = `{"instruments": [{"instrument": "EUR_USD", "halted": true}]}`
+	open, err = client.IsMarketOpen("EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(open, check.Equals, false)
+}
+
+func (s *RatesSuite) TestCandleGaps(c *check.C) {
+	// Thursday 2015-03-12 20:50 UTC.
+	start := time.Date(2015, 3, 12, 20, 50, 0, 0, time.UTC)
+	times := []time.Time{
+		start,
+		start.Add(5 * time.Minute),
+		start.Add(15 * time.Minute), // missing candle
+		start.Add(20 * time.Minute),
+	}
+	c.Assert(oanda.CandleGaps(oanda.M5, times), check.DeepEquals, []int{2})
+
+	// Friday 2015-03-13 20:55 UTC until Sunday 2015-03-15 21:00 UTC.
+	friday := time.Date(2015, 3, 13, 20, 55, 0, 0, time.UTC)
+	sunday := time.Date(2015, 3, 15, 21, 0, 0, 0, time.UTC)
+	times = []time.Time{friday, sunday, sunday.Add(5 * time.Minute)}
+	c.Assert(oanda.CandleGaps(oanda.M5, times), check.HasLen, 0)
+
+	times = []time.Time{friday.Add(-time.Hour), sunday}
+	c.Assert(oanda.CandleGaps(oanda.M5, times), check.DeepEquals, []int{1})
+
+	// Daily candles from Thursday until Monday.
+	thursday := time.Date(2015, 3, 12, 21, 0, 0, 0, time.UTC)
+	times = []time.Time{thursday, thursday.AddDate(0, 0, 3), thursday.AddDate(0, 0, 4)}
+	c.Assert(oanda.CandleGaps(oanda.D, times), check.HasLen, 0)
+
+	candles := oanda.MidpointCandles{
+		Granularity: oanda.M5,
+		Candles: []oanda.MidpointCandle{
+			{Time: "1426193400000000"},
+			{Time: "1426193700000000"},
+			{Time: "1426194300000000"},
+		},
+	}
+	c.Assert(candles.Gaps(), check.DeepEquals, []int{2})
+}
+
+func (s *RatesSuite) TestPollCloseTable(c *check.C) {
+	bodies := map[string]string{
+		"EUR_USD": `[{"time": "1400025600000000", "closeMid": 1.1, "complete": true},
+			{"time": "1400112000000000", "closeMid": 1.2, "complete": true},
+			{"time": "1400198400000000", "closeMid": 1.3, "complete": false}]`,
+		"USD_JPY": `[{"time": "1400112000000000", "closeMid": 101.5, "complete": true}]`,
+	}
+	tr := stubTransport{
+		StatusCode: 200,
+		BodyFunc: func(req *http.Request) string {
+			instr := req.URL.Query().Get("instrument")
+			return fmt.Sprintf(`{"instrument": "%s", "granularity": "D", "candles": %s}`, instr,
+				bodies[instr])
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	ct, err := client.PollCloseTable(oanda.D, []string{"eur_usd", "usd/jpy"}, oanda.Count(3))
+	c.Assert(err, check.IsNil)
+	c.Assert(ct.Instruments, check.DeepEquals, []string{"EUR_USD", "USD_JPY"})
+	c.Assert(ct.Times, check.HasLen, 2)
+	c.Assert(ct.Times[0].Equal(time.Unix(1400025600, 0)), check.Equals, true)
+	c.Assert(ct.Times[1].Equal(time.Unix(1400112000, 0)), check.Equals, true)
+	c.Assert(ct.Closes[0][0], check.Equals, 1.1)
+	c.Assert(math.IsNaN(ct.Closes[0][1]), check.Equals, true)
+	c.Assert(ct.Closes[1], check.DeepEquals, []float64{1.2, 101.5})
+	c.Assert(ct.Column("eur_usd"), check.DeepEquals, []float64{1.1, 1.2})
+	c.Assert(ct.Column("GBP_USD"), check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 2)
+	c.Assert(tr.Requests[0].URL.Query().Get("count"), check.Equals, "3")
+}
+
+func (s *RatesSuite) TestCandleStreamer(c *check.C) {
+	// The stub returns the two most recent complete S5 candles and the incomplete current one.
+	candleJSON := func(t time.Time, complete bool) string {
+		return fmt.Sprintf(`{"time": "%d", "closeMid": 1.1, "complete": %v}`,
+			t.UnixNano()/1000, complete)
+	}
+	tr := stubTransport{
+		StatusCode: 200,
+		BodyFunc: func(req *http.Request) string {
+			cur := time.Now().Truncate(5 * time.Second)
+			return fmt.Sprintf(`{"instrument": "EUR_USD", "granularity": "S5", "candles": [%s, %s, %s]}`,
+				candleJSON(cur.Add(-10*time.Second), true),
+				candleJSON(cur.Add(-5*time.Second), true),
+				candleJSON(cur, false))
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.NewCandleStreamer("eur_usd", oanda.M)
+	c.Assert(err, check.NotNil)
+
+	cs, err := client.NewCandleStreamer("eur_usd", oanda.S5)
+	c.Assert(err, check.IsNil)
+	cs.Delay = 100 * time.Millisecond
+
+	start := time.Now()
+	var received []oanda.MidpointCandle
+	err = cs.Run(func(instr string, candle oanda.MidpointCandle) {
+		c.Assert(instr, check.Equals, "EUR_USD")
+		received = append(received, candle)
+		cs.Stop()
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(received, check.HasLen, 1)
+	c.Assert(received[0].Complete, check.Equals, true)
+	c.Assert(received[0].Time.Time().Before(start.Truncate(5*time.Second)), check.Equals, false)
+	c.Assert(time.Since(start) < 6*time.Second, check.Equals, true)
+	c.Assert(tr.Requests[0].URL.Query().Get("granularity"), check.Equals, "S5")
+}
+
 func (ts *TestRatesSuite) TestRatesInstruments(c *check.C) {
 	instruments, err := ts.Client.Instruments(nil, nil)
 	c.Assert(err, check.IsNil)
@@ -91,3 +308,318 @@ func (ts *TestRatesSuite) TestRatesBidAskCandles(c *check.C) {
 	c.Assert(candles.Granularity, check.Equals, granularity)
 	c.Assert(len(candles.Candles) > 0, check.Equals, true)
 }
+
+func (s *RatesSuite) TestFormatPrice(c *check.C) {
+	eurUsd := oanda.InstrumentInfo{Pip: 0.0001, Precision: 0.00001}
+	c.Assert(eurUsd.Decimals(), check.Equals, 5)
+	c.Assert(eurUsd.FormatPrice(1.2), check.Equals, "1.20000")
+	c.Assert(eurUsd.FormatPrice(1.123456), check.Equals, "1.12346")
+
+	usdJpy := oanda.InstrumentInfo{Pip: 0.01, Precision: 0.001}
+	c.Assert(usdJpy.FormatPrice(120), check.Equals, "120.000")
+
+	pipOnly := oanda.InstrumentInfo{Pip: 0.01}
+	c.Assert(pipOnly.FormatPrice(120), check.Equals, "120.000")
+
+	unknown := oanda.InstrumentInfo{}
+	c.Assert(unknown.FormatPrice(1.2), check.Equals, "1.2")
+}
+
+func (s *RatesSuite) TestPips(c *check.C) {
+	eurUsd := oanda.InstrumentInfo{Pip: 0.0001}
+	c.Assert(eurUsd.PriceToPips(0.0010), check.Equals, 10.0)
+	c.Assert(eurUsd.PriceToPips(0.0003), check.Equals, 3.0)
+	c.Assert(eurUsd.PriceToPips(-0.00015), check.Equals, -1.5)
+	c.Assert(math.Abs(eurUsd.PipsToPrice(10)-0.001) < 1e-12, check.Equals, true)
+
+	usdJpy := oanda.InstrumentInfo{Pip: 0.01}
+	c.Assert(usdJpy.PriceToPips(0.0010), check.Equals, 0.1)
+	c.Assert(usdJpy.PriceToPips(0.25), check.Equals, 25.0)
+	c.Assert(math.Abs(usdJpy.PipsToPrice(25)-0.25) < 1e-12, check.Equals, true)
+
+	unknown := oanda.InstrumentInfo{}
+	c.Assert(math.IsNaN(unknown.PriceToPips(0.001)), check.Equals, true)
+	c.Assert(math.IsNaN(unknown.PipsToPrice(10)), check.Equals, true)
+}
+
+func (s *RatesSuite) TestCandleWindow(c *check.C) {
+	candle := func(minute int64, close float64, complete bool) oanda.MidpointCandle {
+		t := oanda.Time(strconv.FormatInt((1400000000+60*minute)*1000000, 10))
+		return oanda.MidpointCandle{Time: t, CloseMid: close, Complete: complete}
+	}
+
+	cw := oanda.NewCandleWindow(3, oanda.ExcludeIncomplete)
+	cw.Update(candle(0, 1.0, true), candle(1, 1.1, false))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.0})
+	cw.Update(candle(1, 1.2, false))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.0})
+	cw.Update(candle(1, 1.3, true), candle(1, 1.4, true), candle(0, 0.9, true))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.3, 1.0})
+
+	cw = oanda.NewCandleWindow(3, oanda.ReplaceIncomplete)
+	cw.Update(candle(0, 1.0, true), candle(1, 1.1, false))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.1, 1.0})
+	cw.Update(candle(1, 1.2, false))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.2, 1.0})
+	cw.Update(candle(1, 1.3, true), candle(1, 1.4, true))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.3, 1.0})
+	cw.Update(candle(2, 1.5, false))
+	c.Assert(cw.Window().Values(), check.DeepEquals, []float64{1.5, 1.3, 1.0})
+}
+
+func (s *RatesSuite) TestPollLastCompleteCandles(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"instrument": "EUR_USD", "granularity": "M5", "candles": [
+			{"time": "1400000000000000", "closeMid": 1.1, "complete": true},
+			{"time": "1400000300000000", "closeMid": 1.2, "complete": true},
+			{"time": "1400000600000000", "closeMid": 1.3, "complete": false}]}`,
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.PollLastCompleteCandles("eur_usd", oanda.M5, 0)
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	candles, err := client.PollLastCompleteCandles("eur_usd", oanda.M5, 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests[0].URL.Query().Get("count"), check.Equals, "3")
+	c.Assert(candles.Candles, check.HasLen, 2)
+	c.Assert(candles.Candles[0].CloseMid, check.Equals, 1.1)
+	c.Assert(candles.Candles[1].CloseMid, check.Equals, 1.2)
+
+	candles, err = client.PollLastCompleteCandles("eur_usd", oanda.M5, 1)
+	c.Assert(err, check.IsNil)
+	c.Assert(candles.Candles, check.HasLen, 1)
+	c.Assert(candles.Candles[0].CloseMid, check.Equals, 1.2)
+}
+
+func (s *RatesSuite) TestInstrumentsByCurrency(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"instruments": [
+			{"instrument": "USD_JPY"}, {"instrument": "EUR_USD"}, {"instrument": "EUR_JPY"},
+			{"instrument": "GBP_USD"}, {"instrument": "EUR_GBP"}]}`,
+	}
+	client := newStubClient(c, &tr)
+
+	byBase, err := client.InstrumentsByBase()
+	c.Assert(err, check.IsNil)
+	c.Assert(byBase, check.DeepEquals, map[string][]string{
+		"EUR": {"EUR_GBP", "EUR_JPY", "EUR_USD"},
+		"GBP": {"GBP_USD"},
+		"USD": {"USD_JPY"},
+	})
+
+	byQuote, err := client.InstrumentsByQuote()
+	c.Assert(err, check.IsNil)
+	c.Assert(byQuote, check.DeepEquals, map[string][]string{
+		"GBP": {"EUR_GBP"},
+		"JPY": {"EUR_JPY", "USD_JPY"},
+		"USD": {"EUR_USD", "GBP_USD"},
+	})
+
+	jpy, err := client.CurrenciesInvolving("jpy")
+	c.Assert(err, check.IsNil)
+	c.Assert(jpy, check.DeepEquals, []string{"EUR_JPY", "USD_JPY"})
+
+	chf, err := client.CurrenciesInvolving("CHF")
+	c.Assert(err, check.IsNil)
+	c.Assert(chf, check.HasLen, 0)
+}
+
+func (s *RatesSuite) TestCandlesIsStale(c *check.C) {
+	at := func(t time.Time) oanda.Time {
+		return oanda.Time(strconv.FormatInt(t.UnixNano()/1000, 10))
+	}
+	now := time.Now().Truncate(time.Second)
+
+	candles := oanda.MidpointCandles{Granularity: oanda.M5}
+	c.Assert(candles.IsStale(time.Hour), check.Equals, true)
+
+	candles.Candles = []oanda.MidpointCandle{
+		{Time: at(now.Add(-50 * time.Hour))},
+		{Time: at(now.Add(-48 * time.Hour))},
+	}
+	c.Assert(candles.IsStale(time.Hour), check.Equals, true)
+	c.Assert(candles.IsStale(72*time.Hour), check.Equals, false)
+	c.Assert(candles.IsStaleAt(now.Add(-47*time.Hour), time.Hour), check.Equals, false)
+	c.Assert(candles.IsStaleAt(now.Add(-46*time.Hour), time.Hour), check.Equals, true)
+
+	bidAsk := oanda.BidAskCandles{Granularity: oanda.M5}
+	c.Assert(bidAsk.IsStale(time.Hour), check.Equals, true)
+	c.Assert(bidAsk.IsStaleAt(now, time.Hour), check.Equals, true)
+	bidAsk.Candles = []oanda.BidAskCandle{{Time: at(now.Add(-time.Minute))}}
+	c.Assert(bidAsk.IsStale(time.Hour), check.Equals, false)
+	c.Assert(bidAsk.IsStaleAt(now.Add(2*time.Hour), time.Hour), check.Equals, true)
+}
+
+func (s *RatesSuite) TestAllGranularities(c *check.C) {
+	c.Assert(oanda.AllGranularities, check.HasLen, 21)
+	for i, g := range oanda.AllGranularities {
+		c.Assert(g.IsValid(), check.Equals, true)
+		if i > 0 && g != oanda.M {
+			c.Assert(g.Duration() > oanda.AllGranularities[i-1].Duration(), check.Equals, true)
+		}
+	}
+	c.Assert(oanda.Granularity("m5").IsValid(), check.Equals, false)
+	c.Assert(oanda.Granularity("").IsValid(), check.Equals, false)
+}
+
+func (s *RatesSuite) TestCandlesCountStartEnd(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"instrument": "EUR_USD", "candles": []}`}
+	client := newStubClient(c, &tr)
+
+	start := oanda.StartTime(time.Unix(1400000000, 0))
+	end := oanda.EndTime(time.Unix(1400003600, 0))
+	for _, args := range [][]oanda.CandlesArg{
+		{oanda.Count(10)},
+		{start},
+		{end},
+		{oanda.Count(10), start},
+		{oanda.Count(10), end},
+		{start, end},
+	} {
+		_, err := client.PollMidpointCandles("eur_usd", oanda.M1, args...)
+		c.Assert(err, check.IsNil, check.Commentf("args: %v", args))
+		_, err = client.PollBidAskCandles("eur_usd", oanda.M1, args...)
+		c.Assert(err, check.IsNil, check.Commentf("args: %v", args))
+	}
+	c.Assert(tr.Requests, check.HasLen, 12)
+
+	for _, args := range [][]oanda.CandlesArg{
+		{oanda.Count(10), start, end},
+		{end, start, oanda.Count(10)},
+	} {
+		_, err := client.PollMidpointCandles("eur_usd", oanda.M1, args...)
+		c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+		c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "count")
+		c.Assert(err, check.ErrorMatches, ".*Count.*StartTime.*EndTime.*")
+		_, err = client.PollBidAskCandles("eur_usd", oanda.M1, args...)
+		c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	}
+	_, err := client.PollMidpointCandlesAround("eur_usd", oanda.M1, time.Unix(1400000000, 0),
+		time.Hour, oanda.Count(10))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 12)
+}
+
+func (s *RatesSuite) TestNextClose(c *check.C) {
+	now := time.Date(2014, 5, 13, 10, 2, 30, 0, time.UTC)
+	c.Assert(oanda.M5.NextClose(now, 0, time.UTC), check.DeepEquals,
+		time.Date(2014, 5, 13, 10, 5, 0, 0, time.UTC))
+	c.Assert(oanda.M5.NextClose(now.Add(150*time.Second), 0, nil), check.DeepEquals,
+		time.Date(2014, 5, 13, 10, 10, 0, 0, time.UTC))
+	c.Assert(oanda.H1.NextClose(now, 0, time.UTC), check.DeepEquals,
+		time.Date(2014, 5, 13, 11, 0, 0, 0, time.UTC))
+
+	ny := time.FixedZone("EDT", -4*3600)
+	nyNow := time.Date(2014, 5, 13, 18, 0, 0, 0, ny)
+	c.Assert(oanda.H4.NextClose(nyNow, 17, ny), check.DeepEquals, time.Date(2014, 5, 13, 21, 0, 0, 0, ny))
+	c.Assert(oanda.H4.NextClose(nyNow.Add(-2*time.Hour), 17, ny), check.DeepEquals,
+		time.Date(2014, 5, 13, 17, 0, 0, 0, ny))
+	c.Assert(oanda.D.NextClose(nyNow, 17, ny), check.DeepEquals, time.Date(2014, 5, 14, 17, 0, 0, 0, ny))
+	c.Assert(oanda.W.NextClose(nyNow, 17, ny), check.DeepEquals, time.Date(2014, 5, 16, 17, 0, 0, 0, ny))
+	c.Assert(oanda.W.NextClose(time.Date(2014, 5, 16, 17, 0, 0, 0, ny), 17, ny), check.DeepEquals,
+		time.Date(2014, 5, 23, 17, 0, 0, 0, ny))
+	c.Assert(oanda.M.NextClose(nyNow, 17, ny), check.DeepEquals, time.Date(2014, 6, 1, 17, 0, 0, 0, ny))
+	c.Assert(oanda.Granularity("X").NextClose(now, 0, nil).IsZero(), check.Equals, true)
+}
+
+func (s *RatesSuite) TestCandleReturns(c *check.C) {
+	// Daily candles aligned at 17:00 New York time from Wednesday 2015-03-11 until Tuesday
+	// 2015-03-17.  The candle that starts on Thursday is followed by the one that starts on Sunday.
+	day := func(d int) oanda.Time {
+		t := time.Date(2015, 3, d, 21, 0, 0, 0, time.UTC)
+		return oanda.Time(strconv.FormatInt(t.UnixNano()/1000, 10))
+	}
+	candles := oanda.MidpointCandles{
+		Granularity: oanda.D,
+		Candles: []oanda.MidpointCandle{
+			{Time: day(11), CloseMid: 1.00},
+			{Time: day(12), CloseMid: 1.10},
+			{Time: day(15), CloseMid: 1.21},
+			{Time: day(16), CloseMid: 1.21},
+		},
+	}
+	returns := candles.Returns()
+	c.Assert(returns, check.HasLen, 3)
+	c.Assert(math.Abs(returns[0].Simple-0.1) < 1e-9, check.Equals, true)
+	c.Assert(math.Abs(returns[0].Log-math.Log(1.1)) < 1e-9, check.Equals, true)
+	c.Assert(returns[0].SpansWeekend, check.Equals, false)
+	c.Assert(returns[1].SpansWeekend, check.Equals, true)
+	c.Assert(returns[1].Time, check.Equals, day(15).Time())
+	c.Assert(returns[2].SpansWeekend, check.Equals, false)
+	c.Assert(returns[2].Simple, check.Equals, 0.0)
+
+	excl := returns.ExcludeWeekends()
+	c.Assert(excl, check.HasLen, 2)
+	c.Assert(excl.Simple(), check.HasLen, 2)
+	c.Assert(excl.Log()[1], check.Equals, 0.0)
+
+	// Intraday candles across the weekend.
+	friday := time.Date(2015, 3, 13, 20, 55, 0, 0, time.UTC)
+	sunday := time.Date(2015, 3, 15, 21, 0, 0, 0, time.UTC)
+	returns = oanda.ComputeCandleReturns(oanda.M5,
+		[]time.Time{friday.Add(-5 * time.Minute), friday, sunday, sunday.Add(5 * time.Minute)},
+		[]float64{1, 1, 1, 1})
+	c.Assert(returns, check.HasLen, 3)
+	c.Assert(returns[0].SpansWeekend, check.Equals, false)
+	c.Assert(returns[1].SpansWeekend, check.Equals, true)
+	c.Assert(returns[2].SpansWeekend, check.Equals, false)
+
+	// Weekly candles always include a weekend.
+	returns = oanda.ComputeCandleReturns(oanda.W,
+		[]time.Time{friday, friday.AddDate(0, 0, 7)}, []float64{1, 2})
+	c.Assert(returns[0].SpansWeekend, check.Equals, false)
+}
+
+func (s *RatesSuite) TestInstrumentInfoJSON(c *check.C) {
+	info := oanda.InstrumentInfo{
+		DisplayName: "EUR/USD",
+		Pip:         0.0001,
+		Precision:   0.00001,
+		InterestRate: map[string]oanda.InterestRate{
+			"USD": {Bid: 0.1, Ask: 0.2},
+			"EUR": {Bid: -0.1, Ask: 0.05},
+			"CHF": {Bid: -0.75, Ask: -0.5},
+		},
+	}
+	first, err := json.Marshal(info)
+	c.Assert(err, check.IsNil)
+	for i := 0; i < 10; i++ {
+		data, err := json.Marshal(info)
+		c.Assert(err, check.IsNil)
+		c.Assert(string(data), check.Equals, string(first))
+	}
+	c.Assert(strings.Index(string(first), `"CHF"`) < strings.Index(string(first), `"EUR"`),
+		check.Equals, true)
+	c.Assert(strings.Index(string(first), `"EUR"`) < strings.Index(string(first), `"USD"`),
+		check.Equals, true)
+
+	decoded := oanda.InstrumentInfo{}
+	c.Assert(json.Unmarshal(first, &decoded), check.IsNil)
+	c.Assert(decoded, check.DeepEquals, info)
+}
+
+func (s *RatesSuite) TestPollMidpointCandlesAround(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"instrument": "EUR_USD", "granularity": "M5",
+		"candles": [{"time": "1400000000000000", "closeMid": 1.1, "complete": true}]}`}
+	client := newStubClient(c, &tr)
+
+	evt := oanda.CalendarEvent{Title: "Non-Farm Payrolls", Timestamp: 1400000000}
+	candles, err := client.PollMidpointCandlesAround("eur_usd", oanda.M5, evt.Time(),
+		2*time.Hour, oanda.IncludeFirst(false))
+	c.Assert(err, check.IsNil)
+	c.Assert(candles.Candles, check.HasLen, 1)
+
+	c.Assert(tr.Requests, check.HasLen, 1)
+	q := tr.Requests[0].URL.Query()
+	c.Assert(q.Get("granularity"), check.Equals, "M5")
+	c.Assert(q.Get("start"), check.Equals, "1399992800")
+	c.Assert(q.Get("end"), check.Equals, "1400007200")
+	c.Assert(q.Get("includeFirst"), check.Equals, "false")
+
+	_, err = client.PollMidpointCandlesAround("eur_usd", oanda.M5, evt.Time(), 0)
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
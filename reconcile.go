@@ -0,0 +1,201 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A Discrepancy is a difference between the state of an account as derived from its transaction
+// history and the state as reported by Oanda.
+type Discrepancy struct {
+	// Kind is "trade", "order" or "position".
+	Kind string
+	// Id is the id of the trade or order, or 0 for a position.
+	Id         Id
+	Instrument string
+	Reason     string
+}
+
+// String implements the fmt.Stringer interface.
+func (d Discrepancy) String() string {
+	if d.Id == 0 {
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Instrument, d.Reason)
+	}
+	return fmt.Sprintf("%s %d (%s): %s", d.Kind, d.Id, d.Instrument, d.Reason)
+}
+
+// derivedTrade is an open trade as derived from the transaction history.
+type derivedTrade struct {
+	Instrument string
+	Side       string
+	Units      int
+}
+
+// Reconcile replays the complete transaction history of the selected account and compares the
+// open trades, orders and positions that it implies with those that Oanda reports.  An empty
+// result means that the account is consistent.  Reconcile requires that the transaction history
+// is available since the account was created.
+func (c *Client) Reconcile() ([]Discrepancy, error) {
+	if c.accountId == 0 {
+		return nil, &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	events, err := c.eventHistory(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	trades, orders := foldEvents(events)
+
+	liveTrades, err := c.allTrades()
+	if err != nil {
+		return nil, err
+	}
+	liveOrders, err := c.allOrders()
+	if err != nil {
+		return nil, err
+	}
+	livePositions, err := c.Positions()
+	if err != nil {
+		return nil, err
+	}
+
+	var ds []Discrepancy
+	for _, t := range liveTrades {
+		dt, ok := trades[t.TradeId]
+		switch {
+		case !ok:
+			ds = append(ds, Discrepancy{"trade", t.TradeId, t.Instrument,
+				"Open at Oanda but not in the transaction history."})
+		case dt.Units != t.Units:
+			ds = append(ds, Discrepancy{"trade", t.TradeId, t.Instrument,
+				fmt.Sprintf("%d units at Oanda but %d in the transaction history.", t.Units, dt.Units)})
+		}
+		delete(trades, t.TradeId)
+	}
+	tradeIds := make(idSlice, 0, len(trades))
+	for id := range trades {
+		tradeIds = append(tradeIds, id)
+	}
+	sort.Sort(tradeIds)
+	for _, id := range tradeIds {
+		ds = append(ds, Discrepancy{"trade", id, trades[id].Instrument,
+			"Open in the transaction history but not at Oanda."})
+	}
+
+	for _, o := range liveOrders {
+		if _, ok := orders[o.OrderId]; !ok {
+			ds = append(ds, Discrepancy{"order", o.OrderId, o.Instrument,
+				"Open at Oanda but not in the transaction history."})
+		}
+		delete(orders, o.OrderId)
+	}
+	orderIds := make(idSlice, 0, len(orders))
+	for id := range orders {
+		orderIds = append(orderIds, id)
+	}
+	sort.Sort(orderIds)
+	for _, id := range orderIds {
+		ds = append(ds, Discrepancy{"order", id, orders[id],
+			"Open in the transaction history but not at Oanda."})
+	}
+
+	ds = append(ds, reconcilePositions(liveTrades, livePositions)...)
+	return ds, nil
+}
+
+// foldEvents replays events in chronological order and returns the trades and the instruments of
+// the orders that remain open.
+func foldEvents(events []Event) (map[Id]derivedTrade, map[Id]string) {
+	trades := make(map[Id]derivedTrade)
+	orders := make(map[Id]string)
+
+	reduce := func(td *evtTradeDetail) {
+		if td == nil {
+			return
+		}
+		if dt, ok := trades[td.TradeId()]; ok {
+			dt.Units -= td.Units()
+			if dt.Units <= 0 {
+				delete(trades, td.TradeId())
+			} else {
+				trades[td.TradeId()] = dt
+			}
+		}
+	}
+	open := func(td *evtTradeDetail, instrument, side string) {
+		if td != nil {
+			trades[td.TradeId()] = derivedTrade{instrument, side, td.Units()}
+		}
+	}
+
+	for _, evt := range events {
+		switch e := evt.(type) {
+		case *TradeCreateEvent:
+			reduce(e.TradeReduced())
+			open(e.TradeOpened(), e.Instrument(), e.Side())
+		case *OrderCreateEvent:
+			orders[e.TranId()] = e.Instrument()
+		case *OrderFilledEvent:
+			delete(orders, e.OrderId())
+			reduce(e.TradeReduced())
+			open(e.TradeOpened(), e.Instrument(), e.Side())
+		case *OrderCancelEvent:
+			delete(orders, e.OrderId())
+		case *TradeCloseEvent:
+			delete(trades, e.TradeId())
+		case *MigrateTradeOpenEvent:
+			trades[e.TranId()] = derivedTrade{e.Instrument(), e.Side(), e.Units()}
+		}
+	}
+	return trades, orders
+}
+
+// reconcilePositions compares the positions that Oanda reports with the positions that are implied
+// by the open trades.
+func reconcilePositions(trades Trades, positions Positions) []Discrepancy {
+	type key struct{ instrument, side string }
+	implied := make(map[key]int)
+	for _, t := range trades {
+		implied[key{t.Instrument, t.Side}] += t.Units
+	}
+
+	var ds []Discrepancy
+	for _, p := range positions {
+		k := key{p.Instrument, p.Side}
+		if units := implied[k]; units != p.Units {
+			ds = append(ds, Discrepancy{"position", 0, p.Instrument,
+				fmt.Sprintf("%d units %s at Oanda but open trades total %d.", p.Units, p.Side, units)})
+		}
+		delete(implied, k)
+	}
+
+	for _, t := range trades {
+		k := key{t.Instrument, t.Side}
+		if units, ok := implied[k]; ok {
+			ds = append(ds, Discrepancy{"position", 0, k.instrument,
+				fmt.Sprintf("No position at Oanda but open trades total %d units %s.", units, k.side)})
+			delete(implied, k)
+		}
+	}
+	return ds
+}
+
+type idSlice []Id
+
+func (ids idSlice) Len() int           { return len(ids) }
+func (ids idSlice) Less(i, j int) bool { return ids[i] < ids[j] }
+func (ids idSlice) Swap(i, j int)      { ids[i], ids[j] = ids[j], ids[i] }
@@ -0,0 +1,102 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type ReconcileSuite struct{}
+
+var _ = check.Suite(&ReconcileSuite{})
+
+// reconcileHistory opens trades 10 and 14 and orders 11 and 12, cancels order 12, reduces trade 10
+// to 80 units and closes trade 14.
+const reconcileHistory = `{"transactions": [
+	{"id": 16, "type": "TRADE_CLOSE", "time": "1400001600000000", "tradeId": 14,
+	 "instrument": "EUR_USD", "units": 50, "side": "buy"},
+	{"id": 15, "type": "MARKET_ORDER_CREATE", "time": "1400001500000000",
+	 "instrument": "EUR_USD", "units": 20, "side": "sell", "tradeReduced": {"id": 10, "units": 20}},
+	{"id": 14, "type": "MARKET_ORDER_CREATE", "time": "1400001400000000",
+	 "instrument": "EUR_USD", "units": 50, "side": "buy", "tradeOpened": {"id": 14, "units": 50}},
+	{"id": 13, "type": "ORDER_CANCEL", "time": "1400001300000000", "orderId": 12},
+	{"id": 12, "type": "LIMIT_ORDER_CREATE", "time": "1400001200000000",
+	 "instrument": "GBP_USD", "units": 10, "side": "buy"},
+	{"id": 11, "type": "LIMIT_ORDER_CREATE", "time": "1400001100000000",
+	 "instrument": "EUR_USD", "units": 10, "side": "buy"},
+	{"id": 10, "type": "MARKET_ORDER_CREATE", "time": "1400001000000000",
+	 "instrument": "EUR_USD", "units": 100, "side": "buy", "tradeOpened": {"id": 10, "units": 100}}]}`
+
+func (s *ReconcileSuite) TestReconcile(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/transactions": reconcileHistory,
+			"/v1/accounts/1/trades": `{"trades": [
+				{"id": 10, "instrument": "EUR_USD", "side": "buy", "units": 80}]}`,
+			"/v1/accounts/1/orders": `{"orders": [
+				{"id": 11, "instrument": "EUR_USD", "side": "buy", "units": 10}]}`,
+			"/v1/accounts/1/positions": `{"positions": [
+				{"instrument": "EUR_USD", "side": "buy", "units": 80}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.Reconcile()
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	client.SelectAccount(1)
+	ds, err := client.Reconcile()
+	c.Assert(err, check.IsNil)
+	c.Assert(ds, check.HasLen, 0)
+}
+
+func (s *ReconcileSuite) TestReconcileDiscrepancies(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/transactions": reconcileHistory,
+			"/v1/accounts/1/trades": `{"trades": [
+				{"id": 20, "instrument": "USD_JPY", "side": "sell", "units": 5},
+				{"id": 10, "instrument": "EUR_USD", "side": "buy", "units": 70}]}`,
+			"/v1/accounts/1/orders": `{"orders": []}`,
+			"/v1/accounts/1/positions": `{"positions": [
+				{"instrument": "EUR_USD", "side": "buy", "units": 80}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	ds, err := client.Reconcile()
+	c.Assert(err, check.IsNil)
+	for _, d := range ds {
+		c.Log(d)
+	}
+	c.Assert(ds, check.HasLen, 5)
+
+	c.Assert(ds[0].Kind, check.Equals, "trade")
+	c.Assert(ds[0].Id, check.Equals, oanda.Id(20))
+	c.Assert(ds[1].Kind, check.Equals, "trade")
+	c.Assert(ds[1].Id, check.Equals, oanda.Id(10))
+	c.Assert(ds[1].Reason, check.Matches, "70 units at Oanda but 80 .*")
+	c.Assert(ds[2].Kind, check.Equals, "order")
+	c.Assert(ds[2].Id, check.Equals, oanda.Id(11))
+	c.Assert(ds[3].Kind, check.Equals, "position")
+	c.Assert(ds[3].Instrument, check.Equals, "EUR_USD")
+	c.Assert(ds[4].Kind, check.Equals, "position")
+	c.Assert(ds[4].Instrument, check.Equals, "USD_JPY")
+}
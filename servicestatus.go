@@ -0,0 +1,109 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/santegoeds/oanda/status"
+)
+
+// NormalStatusLevel is the level of the status of a service that operates normally.
+const NormalStatusLevel = "NORMAL"
+
+// ServiceHealth summarizes the current status of the Oanda trading and streaming services as
+// reported by the status package.
+type ServiceHealth struct {
+	// Services holds the trading and streaming services.
+	Services []status.ApiService
+	// Degraded holds the services whose current status is not at NormalStatusLevel.
+	Degraded []status.ApiService
+}
+
+// String implements the fmt.Stringer interface.
+func (sh ServiceHealth) String() string {
+	names := make([]string, len(sh.Degraded))
+	for i, svc := range sh.Degraded {
+		names[i] = svc.Name
+	}
+	return fmt.Sprintf("ServiceHealth{Services: %d, Degraded: [%s]}", len(sh.Services),
+		strings.Join(names, ", "))
+}
+
+// IsOperational returns true if all trading and streaming services operate normally.
+func (sh ServiceHealth) IsOperational() bool {
+	return len(sh.Degraded) == 0
+}
+
+// defaultStatusTimeout bounds the duration of CheckServiceStatus if the Client has no request
+// timeout.
+const defaultStatusTimeout = 10 * time.Second
+
+// CheckServiceStatus queries the status of the Oanda trading and streaming services, such as the
+// REST and streaming APIs, and reports the services that are degraded; e.g. because of an ongoing
+// incident.  Other services, such as the Oanda website, are ignored.  The query is made with the
+// http.Client of c and is bounded by the request timeout of c or, if there is none, by ten
+// seconds.
+func (c *Client) CheckServiceStatus() (*ServiceHealth, error) {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = defaultStatusTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	services, err := status.NewStatusClient(c.Client).Services(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sh := ServiceHealth{}
+	for _, svc := range services {
+		if !isTradingService(svc) {
+			continue
+		}
+		sh.Services = append(sh.Services, svc)
+		if isServiceDegraded(svc) {
+			sh.Degraded = append(sh.Degraded, svc)
+		}
+	}
+	return &sh, nil
+}
+
+// tradingServiceKeywords identify the services on which trading and streaming depend by their
+// id or name.
+var tradingServiceKeywords = []string{"api", "stream", "trade"}
+
+// isTradingService returns true if svc is a trading or streaming service.
+func isTradingService(svc status.ApiService) bool {
+	id, name := strings.ToLower(svc.Id), strings.ToLower(svc.Name)
+	for _, kw := range tradingServiceKeywords {
+		if strings.Contains(id, kw) || strings.Contains(name, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// isServiceDegraded returns true if the status of the current event of svc is not normal.  A
+// service without a current event is assumed to operate normally.
+func isServiceDegraded(svc status.ApiService) bool {
+	if svc.CurrentEvent == nil || svc.CurrentEvent.Status == nil {
+		return false
+	}
+	return !strings.EqualFold(svc.CurrentEvent.Status.Level, NormalStatusLevel)
+}
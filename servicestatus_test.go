@@ -0,0 +1,72 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type TestServiceStatusSuite struct {
+	OandaSuite
+}
+
+var _ = check.Suite(&TestServiceStatusSuite{})
+
+func (ts *TestServiceStatusSuite) TestCheckServiceStatus(c *check.C) {
+	sh, err := ts.Client.CheckServiceStatus()
+	c.Assert(err, check.IsNil)
+	c.Log(sh)
+	c.Assert(len(sh.Services) > 0, check.Equals, true)
+	c.Assert(sh.IsOperational(), check.Equals, len(sh.Degraded) == 0)
+}
+
+type ServiceStatusSuite struct{}
+
+var _ = check.Suite(&ServiceStatusSuite{})
+
+func (s *ServiceStatusSuite) TestCheckServiceStatus(c *check.C) {
+	tr := stubTransport{StatusCode: 200, Body: `{"services": [
+		{"id": "rest-api", "name": "REST API",
+		 "current-event": {"status": {"level": "NORMAL"}}},
+		{"id": "streaming-api", "name": "Streaming API",
+		 "current-event": {"status": {"level": "WARNING"}}},
+		{"id": "website", "name": "Website",
+		 "current-event": {"status": {"level": "CRITICAL"}}}]}`}
+	client := newStubClient(c, &tr)
+
+	sh, err := client.CheckServiceStatus()
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests[0].URL.Host, check.Equals, "api-status.oanda.com")
+	c.Assert(sh.Services, check.HasLen, 2)
+	c.Assert(sh.Degraded, check.HasLen, 1)
+	c.Assert(sh.Degraded[0].Id, check.Equals, "streaming-api")
+	c.Assert(sh.IsOperational(), check.Equals, false)
+}
+
+func (s *ServiceStatusSuite) TestCheckServiceStatusTimeout(c *check.C) {
+	client, err := oanda.NewClient("fxpractice", "secret-token",
+		&http.Client{Transport: blockingTransport{}}, oanda.WithRequestTimeout(50*time.Millisecond))
+	c.Assert(err, check.IsNil)
+
+	start := time.Now()
+	_, err = client.CheckServiceStatus()
+	c.Assert(err, check.ErrorMatches, ".*deadline exceeded.*")
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+}
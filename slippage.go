@@ -0,0 +1,130 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Slippage is the difference between the price at which a market order was filled and the
+// midpoint price of the instrument at the time of the fill.  Pips is positive if the fill was
+// worse than the midpoint; i.e. a buy above or a sell below the midpoint.  Note that Pips
+// includes half of the spread.
+type Slippage struct {
+	TranId     Id
+	Time       Time
+	Instrument string
+	Side       string
+	Units      int
+	Price      float64
+	Mid        float64
+	Pips       float64
+}
+
+// String implements the fmt.Stringer interface.
+func (s Slippage) String() string {
+	return fmt.Sprintf("Slippage{TranId: %d, Instrument: %s, Side: %s, Price: %v, Mid: %v, "+
+		"Pips: %v}", s.TranId, s.Instrument, s.Side, s.Price, s.Mid, s.Pips)
+}
+
+// Slippages is a list of the slippage of market orders.
+type Slippages []Slippage
+
+// AvgPips returns the mean slippage in pips, or NaN if the list is empty.
+func (ss Slippages) AvgPips() float64 {
+	if len(ss) == 0 {
+		return math.NaN()
+	}
+	total := 0.0
+	for _, s := range ss {
+		total += s.Pips
+	}
+	return total / float64(len(ss))
+}
+
+// Slippage estimates the slippage of the market order fill evt.  The midpoint price at the time
+// of the fill is taken to be the open of the S5 midpoint candle in which the fill falls or, if no
+// prices were recorded in that interval, the close of the most recent candle before the fill.
+func (c *Client) Slippage(evt *TradeCreateEvent) (*Slippage, error) {
+	instrument := evt.Instrument()
+	info, err := c.InstrumentInfo(instrument)
+	if err != nil {
+		return nil, err
+	}
+	t := evt.Time().Time()
+	candles, err := c.PollMidpointCandlesAround(instrument, S5, t, time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	mid := math.NaN()
+	for _, candle := range candles.Candles {
+		start := candle.Time.Time()
+		if start.After(t) {
+			break
+		}
+		if t.Sub(start) < S5.Duration() {
+			mid = candle.OpenMid
+		} else {
+			mid = candle.CloseMid
+		}
+	}
+	if math.IsNaN(mid) {
+		return nil, fmt.Errorf("No %s prices found before the fill of transaction %d at %s",
+			instrument, evt.TranId(), evt.Time())
+	}
+
+	diff := evt.Price() - mid
+	if TradeSide(evt.Side()) == Sell {
+		diff = -diff
+	}
+	return &Slippage{
+		TranId:     evt.TranId(),
+		Time:       evt.Time(),
+		Instrument: instrument,
+		Side:       evt.Side(),
+		Units:      evt.Units(),
+		Price:      evt.Price(),
+		Mid:        mid,
+		Pips:       info.PriceToPips(diff),
+	}, nil
+}
+
+// MarketOrderSlippage estimates the slippage of every market order of the selected account that
+// was filled since start, in chronological order.  See Slippage.
+func (c *Client) MarketOrderSlippage(start time.Time) (Slippages, error) {
+	if c.accountId == 0 {
+		return nil, &ValidationError{Field: "accountId", Reason: "A selected account is required."}
+	}
+	events, err := c.eventHistory(start)
+	if err != nil {
+		return nil, err
+	}
+	slippages := Slippages{}
+	for _, evt := range events {
+		tce, ok := evt.(*TradeCreateEvent)
+		if !ok {
+			continue
+		}
+		s, err := c.Slippage(tce)
+		if err != nil {
+			return nil, err
+		}
+		slippages = append(slippages, *s)
+	}
+	return slippages, nil
+}
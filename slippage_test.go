@@ -0,0 +1,66 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"math"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type SlippageSuite struct{}
+
+var _ = check.Suite(&SlippageSuite{})
+
+func (s *SlippageSuite) TestMarketOrderSlippage(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/transactions": `{"transactions": [
+				{"id": 3, "type": "MARKET_ORDER_CREATE", "time": "1400000012000000",
+				 "instrument": "EUR_USD", "side": "sell", "units": 10, "price": 1.1003},
+				{"id": 2, "type": "LIMIT_ORDER_CREATE", "time": "1400000008000000",
+				 "instrument": "EUR_USD", "side": "buy", "units": 10, "price": 1.09},
+				{"id": 1, "type": "MARKET_ORDER_CREATE", "time": "1400000006000000",
+				 "instrument": "EUR_USD", "side": "buy", "units": 10, "price": 1.10045}]}`,
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_USD", "pip": "0.0001"}]}`,
+			"/v1/candles": `{"instrument": "EUR_USD", "granularity": "S5", "candles": [
+				{"time": "1400000000000000", "openMid": 1.1, "closeMid": 1.1002, "complete": true},
+				{"time": "1400000005000000", "openMid": 1.1003, "closeMid": 1.1004, "complete": true}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.MarketOrderSlippage(time.Unix(1400000000, 0))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	client.SelectAccount(1)
+	slippages, err := client.MarketOrderSlippage(time.Unix(1400000000, 0))
+	c.Assert(err, check.IsNil)
+	c.Assert(slippages, check.HasLen, 2)
+
+	c.Assert(slippages[0].TranId, check.Equals, oanda.Id(1))
+	c.Assert(slippages[0].Mid, check.Equals, 1.1003)
+	c.Assert(slippages[0].Pips, check.Equals, 1.5)
+	c.Assert(slippages[1].TranId, check.Equals, oanda.Id(3))
+	c.Assert(slippages[1].Mid, check.Equals, 1.1004)
+	c.Assert(slippages[1].Pips, check.Equals, 1.0)
+	c.Assert(slippages.AvgPips(), check.Equals, 1.25)
+
+	c.Assert(math.IsNaN(oanda.Slippages{}.AvgPips()), check.Equals, true)
+}
@@ -20,6 +20,7 @@
 package status
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -39,6 +40,31 @@ func (e *ClientError) Error() string {
 	return fmt.Sprintf("ClientError{Code: %d, Message: %s, IsError: %v}", e.Code, e.Message, e.IsError)
 }
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Client
+
+// DefaultBaseURL is the URL of the Oanda status API.
+const DefaultBaseURL = "http://api-status.oanda.com/api"
+
+// A StatusClient queries the status API with a configurable http.Client.  Every query takes a
+// context.Context that can cancel it, or bound it with a deadline.  The package-level functions
+// use a StatusClient with the default http.Client and context.Background().
+type StatusClient struct {
+	// Client is the http.Client with which the queries are made.  http.DefaultClient is used if
+	// Client is nil.
+	Client *http.Client
+
+	// BaseURL is the URL of the status API.  DefaultBaseURL is used if BaseURL is empty.
+	BaseURL string
+}
+
+// NewStatusClient returns a StatusClient that queries the status API with hc.
+func NewStatusClient(hc *http.Client) *StatusClient {
+	return &StatusClient{Client: hc}
+}
+
+var defaultClient = &StatusClient{}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // Service
 
@@ -52,13 +78,18 @@ type ApiService struct {
 	Url          string           `json:"url"`
 }
 
-// Services returns an array with information about all existing services.
+// Services is StatusClient.Services with a default StatusClient.
 func Services() ([]ApiService, error) {
+	return defaultClient.Services(context.Background())
+}
+
+// Services returns an array with information about all existing services.
+func (sc *StatusClient) Services(ctx context.Context) ([]ApiService, error) {
 	v := struct {
 		ClientError
 		Services []ApiService `json:"services"`
 	}{}
-	if err := getStatus("/v1/services", &v); err != nil {
+	if err := sc.getStatus(ctx, "/v1/services", &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -67,13 +98,18 @@ func Services() ([]ApiService, error) {
 	return v.Services, nil
 }
 
-// Service returns information about the service with the specified service id.
+// Service is StatusClient.Service with a default StatusClient.
 func Service(serviceId string) (*ApiService, error) {
+	return defaultClient.Service(context.Background(), serviceId)
+}
+
+// Service returns information about the service with the specified service id.
+func (sc *StatusClient) Service(ctx context.Context, serviceId string) (*ApiService, error) {
 	v := struct {
 		ClientError
 		ApiService
 	}{}
-	if err := getStatus(fmt.Sprintf("/v1/services/%s", serviceId), &v); err != nil {
+	if err := sc.getStatus(ctx, fmt.Sprintf("/v1/services/%s", serviceId), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -94,13 +130,18 @@ type ApiServiceList struct {
 	Url         string `json:"url"`
 }
 
-// ServiceLists returns an array with information off all defined service lists.
+// ServiceLists is StatusClient.ServiceLists with a default StatusClient.
 func ServiceLists() ([]ApiServiceList, error) {
+	return defaultClient.ServiceLists(context.Background())
+}
+
+// ServiceLists returns an array with information off all defined service lists.
+func (sc *StatusClient) ServiceLists(ctx context.Context) ([]ApiServiceList, error) {
 	v := struct {
 		ClientError
 		Lists []ApiServiceList `json:"lists"`
 	}{}
-	if err := getStatus("/v1/service-lists", &v); err != nil {
+	if err := sc.getStatus(ctx, "/v1/service-lists", &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -109,13 +150,18 @@ func ServiceLists() ([]ApiServiceList, error) {
 	return v.Lists, nil
 }
 
-// ServiceList returns information about the service list with the specified service id.
+// ServiceList is StatusClient.ServiceList with a default StatusClient.
 func ServiceList(serviceId string) (*ApiServiceList, error) {
+	return defaultClient.ServiceList(context.Background(), serviceId)
+}
+
+// ServiceList returns information about the service list with the specified service id.
+func (sc *StatusClient) ServiceList(ctx context.Context, serviceId string) (*ApiServiceList, error) {
 	v := struct {
 		ClientError
 		ApiServiceList
 	}{}
-	if err := getStatus(fmt.Sprintf("/v1/service-lists/%s", serviceId), &v); err != nil {
+	if err := sc.getStatus(ctx, fmt.Sprintf("/v1/service-lists/%s", serviceId), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -137,12 +183,32 @@ type ApiServiceEvent struct {
 	Informational bool              `json:"informational"`
 }
 
+// timestampLayouts are the layouts with which the Timestamp of an ApiServiceEvent is parsed.
+var timestampLayouts = []string{time.RFC1123, time.RFC1123Z, time.RFC3339}
+
+// Time parses the Timestamp of the event, which is normally formatted as per RFC 1123.
+func (e *ApiServiceEvent) Time() (time.Time, error) {
+	var err error
+	for _, layout := range timestampLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, e.Timestamp); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// ServiceEvents is StatusClient.ServiceEvents with a default StatusClient.
+func ServiceEvents(serviceId string, start *time.Time, end *time.Time) ([]ApiServiceEvent, error) {
+	return defaultClient.ServiceEvents(context.Background(), serviceId, start, end)
+}
+
 // ServiceEvents returns an array of events for the specified service id. If start- and/or end is
 // not nil the list if filtered to include only the events between start- and end time, inclusive.
 //
 // Note that only the date part of the start- and end times considered and parts with finer
 // granularity are ignored.
-func ServiceEvents(serviceId string, start *time.Time, end *time.Time) ([]ApiServiceEvent, error) {
+func (sc *StatusClient) ServiceEvents(ctx context.Context, serviceId string, start *time.Time, end *time.Time) ([]ApiServiceEvent, error) {
 	v := struct {
 		ClientError
 		Events []ApiServiceEvent `json:"events"`
@@ -159,7 +225,7 @@ func ServiceEvents(serviceId string, start *time.Time, end *time.Time) ([]ApiSer
 		q.Set("end", end.Truncate(24*time.Hour).Format(time.RFC1123))
 	}
 	u.RawQuery = q.Encode()
-	if err = getStatus(u.String(), &v); err != nil {
+	if err = sc.getStatus(ctx, u.String(), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -168,14 +234,19 @@ func ServiceEvents(serviceId string, start *time.Time, end *time.Time) ([]ApiSer
 	return v.Events, nil
 }
 
-// CurrentServiceEvent returns event information for the current (i.e. most recent) event.
+// CurrentServiceEvent is StatusClient.CurrentServiceEvent with a default StatusClient.
 func CurrentServiceEvent(serviceId string) (*ApiServiceEvent, error) {
+	return defaultClient.CurrentServiceEvent(context.Background(), serviceId)
+}
+
+// CurrentServiceEvent returns event information for the current (i.e. most recent) event.
+func (sc *StatusClient) CurrentServiceEvent(ctx context.Context, serviceId string) (*ApiServiceEvent, error) {
 	v := struct {
 		Code    int  `json:"code"`
 		IsError bool `json:"error"`
 		ApiServiceEvent
 	}{}
-	if err := getStatus(fmt.Sprintf("/v1/services/%s/events/current", serviceId), &v); err != nil {
+	if err := sc.getStatus(ctx, fmt.Sprintf("/v1/services/%s/events/current", serviceId), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -189,15 +260,20 @@ func CurrentServiceEvent(serviceId string) (*ApiServiceEvent, error) {
 	return &v.ApiServiceEvent, nil
 }
 
+// ServiceEvent is StatusClient.ServiceEvent with a default StatusClient.
+func ServiceEvent(serviceId, eventId string) (*ApiServiceEvent, error) {
+	return defaultClient.ServiceEvent(context.Background(), serviceId, eventId)
+}
+
 // ServiceEvent return information about the service event that matches the specified serviceId
 // and eventId.
-func ServiceEvent(serviceId, eventId string) (*ApiServiceEvent, error) {
+func (sc *StatusClient) ServiceEvent(ctx context.Context, serviceId, eventId string) (*ApiServiceEvent, error) {
 	v := struct {
 		Code    int  `json:"code"`
 		IsError bool `json:"error"`
 		ApiServiceEvent
 	}{}
-	if err := getStatus(fmt.Sprintf("/v1/services/%s/events/%s", serviceId, eventId), &v); err != nil {
+	if err := sc.getStatus(ctx, fmt.Sprintf("/v1/services/%s/events/%s", serviceId, eventId), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -225,13 +301,18 @@ type ApiServiceStatus struct {
 	Default     bool   `json:"default"`
 }
 
-// ServiceStatuses returns an array with status information for each defined service.
+// ServiceStatuses is StatusClient.ServiceStatuses with a default StatusClient.
 func ServiceStatuses() ([]ApiServiceStatus, error) {
+	return defaultClient.ServiceStatuses(context.Background())
+}
+
+// ServiceStatuses returns an array with status information for each defined service.
+func (sc *StatusClient) ServiceStatuses(ctx context.Context) ([]ApiServiceStatus, error) {
 	v := struct {
 		ClientError
 		Statuses []ApiServiceStatus `json:"statuses"`
 	}{}
-	if err := getStatus("/v1/statuses", &v); err != nil {
+	if err := sc.getStatus(ctx, "/v1/statuses", &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -240,13 +321,18 @@ func ServiceStatuses() ([]ApiServiceStatus, error) {
 	return v.Statuses, nil
 }
 
-// ServiceStatus return status information about the service with the specifed id.
+// ServiceStatus is StatusClient.ServiceStatus with a default StatusClient.
 func ServiceStatus(statusId string) (*ApiServiceStatus, error) {
+	return defaultClient.ServiceStatus(context.Background(), statusId)
+}
+
+// ServiceStatus return status information about the service with the specifed id.
+func (sc *StatusClient) ServiceStatus(ctx context.Context, statusId string) (*ApiServiceStatus, error) {
 	v := struct {
 		ClientError
 		ApiServiceStatus
 	}{}
-	if err := getStatus(fmt.Sprintf("/v1/statuses/%s", statusId), &v); err != nil {
+	if err := sc.getStatus(ctx, fmt.Sprintf("/v1/statuses/%s", statusId), &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -264,12 +350,18 @@ type ApiStatusImage struct {
 	Url     string `json:"url"`
 }
 
+// StatusImages is StatusClient.StatusImages with a default StatusClient.
 func StatusImages() ([]ApiStatusImage, error) {
+	return defaultClient.StatusImages(context.Background())
+}
+
+// StatusImages returns an array with the images that represent the status levels.
+func (sc *StatusClient) StatusImages(ctx context.Context) ([]ApiStatusImage, error) {
 	v := struct {
 		ClientError
 		Images []ApiStatusImage `json:"images"`
 	}{}
-	if err := getStatus("/v1/status-images", &v); err != nil {
+	if err := sc.getStatus(ctx, "/v1/status-images", &v); err != nil {
 		return nil, err
 	}
 	if v.IsError {
@@ -281,9 +373,20 @@ func StatusImages() ([]ApiStatusImage, error) {
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // private
 
-func getStatus(urlStr string, v interface{}) error {
-	urlStr = "http://api-status.oanda.com/api" + urlStr
-	rsp, err := http.Get(urlStr)
+func (sc *StatusClient) getStatus(ctx context.Context, urlStr string, v interface{}) error {
+	baseURL := sc.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequest("GET", baseURL+urlStr, nil)
+	if err != nil {
+		return err
+	}
+	hc := sc.Client
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	rsp, err := hc.Do(req.WithContext(ctx))
 	if err != nil {
 		return err
 	}
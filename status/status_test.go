@@ -1,7 +1,12 @@
 package status_test
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/santegoeds/oanda/status"
 	"gopkg.in/check.v1"
@@ -32,6 +37,22 @@ func (ts *TestSuite) TestStatusApi(c *check.C) {
 	c.Log(currentEvent)
 }
 
+func (ts *TestSuite) TestServiceEventTime(c *check.C) {
+	evt := status.ApiServiceEvent{Timestamp: "Mon, 09 Jun 2014 16:04:01 GMT"}
+	t, err := evt.Time()
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2014, 6, 9, 16, 4, 1, 0, time.UTC)), check.Equals, true)
+
+	evt.Timestamp = "2014-06-09T17:04:01+01:00"
+	t, err = evt.Time()
+	c.Assert(err, check.IsNil)
+	c.Assert(t.Equal(time.Date(2014, 6, 9, 16, 4, 1, 0, time.UTC)), check.Equals, true)
+
+	evt.Timestamp = "yesterday"
+	_, err = evt.Time()
+	c.Assert(err, check.NotNil)
+}
+
 func (ts *TestSuite) TestServiceListApi(c *check.C) {
 	serviceLists, err := status.ServiceLists()
 	c.Assert(err, check.IsNil)
@@ -53,3 +74,31 @@ func (ts *TestSuite) TestServiceStatusApi(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Log(status)
 }
+
+func (ts *TestSuite) TestStatusClient(c *check.C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/services":
+			fmt.Fprint(w, `{"services": [{"id": "rest-api", "name": "REST API"}]}`)
+		default:
+			time.Sleep(time.Second)
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer srv.Close()
+
+	sc := status.NewStatusClient(&http.Client{Timeout: 5 * time.Second})
+	sc.BaseURL = srv.URL + "/api"
+
+	services, err := sc.Services(context.Background())
+	c.Assert(err, check.IsNil)
+	c.Assert(services, check.HasLen, 1)
+	c.Assert(services[0].Id, check.Equals, "rest-api")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = sc.ServiceStatuses(ctx)
+	c.Assert(err, check.NotNil)
+	c.Assert(time.Since(start) < time.Second, check.Equals, true)
+}
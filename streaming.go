@@ -19,7 +19,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -44,6 +46,7 @@ type TimedReader struct {
 	rdr     io.Reader
 	closeFn func() error
 	timer   *time.Timer
+	clock   Clock
 }
 
 // NewTimedReader returns an instance of TimedReader where Read operations time out.
@@ -62,6 +65,9 @@ func NewTimedReader(rc io.ReadCloser, timeout time.Duration) *TimedReader {
 }
 
 func (r *TimedReader) Read(p []byte) (int, error) {
+	if r.clock != nil && r.clock != SystemClock {
+		return r.readWithClock(p)
+	}
 	if r.timer == nil {
 		r.timer = time.AfterFunc(r.Timeout, func() { r.Close() })
 	} else {
@@ -72,6 +78,22 @@ func (r *TimedReader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// readWithClock is like Read but times out according to the clock of the TimedReader.
+func (r *TimedReader) readWithClock(p []byte) (int, error) {
+	doneC := make(chan struct{})
+	timeoutC := r.clock.After(r.Timeout)
+	go func() {
+		select {
+		case <-timeoutC:
+			r.Close()
+		case <-doneC:
+		}
+	}()
+	n, err := r.rdr.Read(p)
+	close(doneC)
+	return n, err
+}
+
 func (r *TimedReader) Close() error {
 	return r.closeFn()
 }
@@ -128,6 +150,43 @@ func (ss StreamServer) HandleHeartbeats(hbC <-chan Time) {
 	}
 }
 
+///////////////////////////////////////////////////////////////////////////////////////////////////
+// Backoff
+
+// A BackoffFunc returns the delay before reconnection attempt attempt, starting at 1, after a
+// stream connection failed.  A negative delay stops the reconnection attempts.  A Retry-After
+// delay that Oanda specifies takes precedence over the delay that BackoffFunc returns.
+type BackoffFunc func(attempt int) time.Duration
+
+// DefaultBackoff doubles the delay from one second onwards and stops reconnecting once the delay
+// would reach five minutes.
+func DefaultBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 32 {
+		return -1
+	}
+	delay := time.Second << uint(attempt-1)
+	if delay >= maxDelay {
+		return -1
+	}
+	return delay
+}
+
+// FixedBackoff returns a BackoffFunc that waits delay before every reconnection attempt and never
+// stops reconnecting.
+func FixedBackoff(delay time.Duration) BackoffFunc {
+	return func(int) time.Duration { return delay }
+}
+
+// A ReconnectFunc is invoked by a stream server each time that it is about to retry its
+// connection, either because the connection was lost or because a connection attempt failed.
+// Attempt counts the retries since the stream was last connected, starting at 1, and err is the
+// error that caused the retry.  A ReconnectFunc is not invoked for the initial connection attempt
+// nor after the server is stopped.
+type ReconnectFunc func(attempt int, err error)
+
 ///////////////////////////////////////////////////////////////////////////////////////////////////
 // messageServer
 
@@ -137,7 +196,14 @@ type messageServer struct {
 	mtx          sync.Mutex
 	req          *http.Request
 	runFlg       bool
+	stopC        chan struct{}
 	stallTimeout time.Duration
+	backoff      BackoffFunc
+	reconnectFn  ReconnectFunc
+
+	statMtx       sync.RWMutex
+	lastHeartbeat time.Time
+	lastMessage   time.Time
 }
 
 // newMessageServer returns a new instance of messageServer that forwards each message and
@@ -161,6 +227,9 @@ func (s *messageServer) ConnectAndDispatch() (err error) {
 
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
+	if s.runFlg {
+		close(s.stopC)
+	}
 	s.runFlg = false
 	return
 }
@@ -169,10 +238,29 @@ func (s *messageServer) ConnectAndDispatch() (err error) {
 func (s *messageServer) Stop() {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
+	if s.runFlg {
+		close(s.stopC)
+	}
 	s.runFlg = false
 	cancelRequest(s)
 }
 
+// LastHeartbeat returns the time at which the most recent heartbeat was received or the zero time
+// if no heartbeat has been received.
+func (s *messageServer) LastHeartbeat() time.Time {
+	s.statMtx.RLock()
+	defer s.statMtx.RUnlock()
+	return s.lastHeartbeat
+}
+
+// LastMessage returns the time at which the most recent message other than a heartbeat was
+// received or the zero time if no such message has been received.
+func (s *messageServer) LastMessage() time.Time {
+	s.statMtx.RLock()
+	defer s.statMtx.RUnlock()
+	return s.lastMessage
+}
+
 func (s *messageServer) initServer() error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
@@ -180,6 +268,7 @@ func (s *messageServer) initServer() error {
 		return errors.New("server is already running")
 	}
 	s.runFlg = true
+	s.stopC = make(chan struct{})
 	return nil
 }
 
@@ -194,13 +283,25 @@ func (s *messageServer) readMessages() error {
 
 	newResponse := func() (*http.Response, error) {
 		debug("connecting to %s...\n", s.req.URL.Host)
-		rsp, err := s.c.Do(s.req)
+		rsp, err := s.c.do(s.req)
 		if err != nil {
 			return nil, err
 		}
 		if rsp.StatusCode < 400 {
+			if err = checkStreamContentType(rsp); err != nil {
+				closeResponse(rsp.Body)
+				return nil, err
+			}
 			return rsp, nil
 		}
+		defer closeResponse(rsp.Body)
+		if rsp.StatusCode == http.StatusTooManyRequests ||
+			rsp.StatusCode == http.StatusServiceUnavailable {
+			return nil, &retryAfterError{
+				Status: rsp.Status,
+				Delay:  parseRetryAfter(rsp.Header.Get("Retry-After")),
+			}
+		}
 		apiErr := ApiError{}
 		if err = json.NewDecoder(rsp.Body).Decode(&apiErr); err != nil {
 			return nil, err
@@ -208,27 +309,56 @@ func (s *messageServer) readMessages() error {
 		return nil, &apiErr
 	}
 
+	backoff := s.backoff
+	if backoff == nil {
+		backoff = DefaultBackoff
+	}
+
+	// retries counts the connection attempts since the stream was last connected, not including
+	// the initial connection attempt.
+	retries := 0
+	notifyReconnect := func(err error) {
+		retries++
+		if s.reconnectFn != nil {
+			s.reconnectFn(retries, err)
+		}
+	}
+
 	newReader := func() (rdr io.ReadCloser, err error) {
-		delay := time.Second
-		for {
+		for attempt := 1; ; attempt++ {
 			s.mtx.Lock()
-			runFlg := s.runFlg
+			runFlg, stopC := s.runFlg, s.stopC
+			var wait time.Duration
 			if runFlg {
 				var rsp *http.Response
 				rsp, err = newResponse()
 				if err != nil {
-					_, ok := err.(*ApiError)
-					runFlg = !ok
+					switch err.(type) {
+					case *ApiError, *StreamContentError:
+						runFlg = false
+					}
+					wait = backoff(attempt)
+					if raErr, ok := err.(*retryAfterError); ok && raErr.Delay > 0 && wait >= 0 {
+						wait = raErr.Delay
+					}
 				} else {
-					rdr = NewTimedReader(rsp.Body, s.stallTimeout)
+					tr := NewTimedReader(rsp.Body, s.stallTimeout)
+					tr.clock = s.c.clock()
+					rdr = tr
 				}
 			}
 			s.mtx.Unlock()
-			if !runFlg || rdr != nil || delay >= maxDelay {
+			if rdr != nil {
+				retries = 0
+			}
+			if !runFlg || rdr != nil || wait < 0 {
 				break
 			}
-			time.Sleep(delay)
-			delay *= 2
+			notifyReconnect(err)
+			select {
+			case <-s.c.clock().After(wait):
+			case <-stopC:
+			}
 		}
 		return
 	}
@@ -241,16 +371,30 @@ func (s *messageServer) readMessages() error {
 		dec := json.NewDecoder(rdr)
 
 		msg := StreamMessage{}
-		for {
+		for first := true; ; first = false {
 			err = dec.Decode(&msg)
 			if err != nil {
 				if _, ok := err.(*ApiError); ok {
 					rdr.Close()
 					return err
 				}
+				if _, ok := err.(*json.SyntaxError); ok && first {
+					// The response is not a JSON message stream at all, so reconnecting will
+					// not help.
+					rdr.Close()
+					return newStreamContentError("", dec.Buffered())
+				}
 				break
 			}
 
+			s.statMtx.Lock()
+			if now := s.c.clock().Now(); msg.Type == "heartbeat" {
+				s.lastHeartbeat = now
+			} else {
+				s.lastMessage = now
+			}
+			s.statMtx.Unlock()
+
 			switch msg.Type {
 			default:
 				msgC <- msg
@@ -264,19 +408,116 @@ func (s *messageServer) readMessages() error {
 					hbC <- v.Time
 				}
 			case "disconnect":
-				apiErr := ApiError{}
-				if err = json.Unmarshal(msg.RawMessage, &apiErr); err == nil {
-					err = &apiErr
+				// The server ends the stream deliberately, so the stream is not reconnected.
+				disconnectErr := StreamDisconnectError{}
+				if err = json.Unmarshal(msg.RawMessage, &disconnectErr); err != nil {
+					debug("malformed disconnect message: %s\n", msg.RawMessage)
 				}
-				// FIXME: log msg.AsApiError()
-				s.mtx.Lock()
-				cancelRequest(s)
-				s.mtx.Unlock()
-				break
+				rdr.Close()
+				return &disconnectErr
 			}
 		}
 		rdr.Close()
+
+		s.mtx.Lock()
+		runFlg := s.runFlg
+		s.mtx.Unlock()
+		if runFlg {
+			notifyReconnect(err)
+		}
+	}
+}
+
+// A StreamDisconnectError is returned by a stream server when Oanda sends a disconnect message to
+// end the stream.  The stream is not reconnected after a StreamDisconnectError.
+type StreamDisconnectError struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	MoreInfo string `json:"moreInfo"`
+}
+
+func (e *StreamDisconnectError) Error() string {
+	return fmt.Sprintf("stream disconnected by server: Code: %d, Message: %s, MoreInfo: %s",
+		e.Code, e.Message, e.MoreInfo)
+}
+
+// A StreamContentError is returned when a stream server responds with something other than a
+// stream of JSON messages, such as the HTML maintenance page of a proxy.  The stream is not
+// reconnected after a StreamContentError.
+type StreamContentError struct {
+	// ContentType is the Content-Type of the response, if known.
+	ContentType string
+	// Content holds the start of the response body.
+	Content string
+}
+
+func (e *StreamContentError) Error() string {
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "unknown"
+	}
+	return fmt.Sprintf("stream returned content of type %s instead of JSON: %q", contentType,
+		e.Content)
+}
+
+// maxStreamContentSnippet is the maximum length of StreamContentError.Content.
+const maxStreamContentSnippet = 256
+
+func newStreamContentError(contentType string, body io.Reader) *StreamContentError {
+	buf := make([]byte, maxStreamContentSnippet)
+	n, _ := io.ReadFull(body, buf)
+	return &StreamContentError{
+		ContentType: contentType,
+		Content:     strings.TrimSpace(string(buf[:n])),
+	}
+}
+
+// checkStreamContentType returns a StreamContentError if the Content-Type of rsp shows that the
+// response is a markup document rather than a stream of JSON messages.  A missing Content-Type
+// is accepted.
+func checkStreamContentType(rsp *http.Response) error {
+	contentType := rsp.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "text/xml", "application/xml":
+		return newStreamContentError(mediaType, rsp.Body)
+	}
+	return nil
+}
+
+// retryAfterError is returned when the stream server is temporarily unable to serve a request.
+// Delay is the time to wait before reconnecting as requested by the server, or 0 if the server
+// did not specify a delay.
+type retryAfterError struct {
+	Status string
+	Delay  time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return fmt.Sprintf("%s (retry after %v)", e.Status, e.Delay)
+}
+
+// parseRetryAfter returns the delay specified by the value of a Retry-After header, which is
+// either a number of seconds or an HTTP date.  Zero is returned if the value is invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(time.Now()); d > 0 {
+			return d
+		}
 	}
+	return 0
 }
 
 func cancelRequest(s *messageServer) {
@@ -0,0 +1,97 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santegoeds/oanda/analytics"
+)
+
+// A CurrencyScore is the strength of a currency as calculated by Client.CurrencyStrength.
+type CurrencyScore struct {
+	Currency string
+	Score    float64
+}
+
+func (cs CurrencyScore) String() string {
+	return fmt.Sprintf("%s: %.6f", cs.Currency, cs.Score)
+}
+
+type currencyScores []CurrencyScore
+
+func (cs currencyScores) Len() int { return len(cs) }
+func (cs currencyScores) Less(i, j int) bool {
+	if cs[i].Score != cs[j].Score {
+		return cs[i].Score > cs[j].Score
+	}
+	return cs[i].Currency < cs[j].Currency
+}
+func (cs currencyScores) Swap(i, j int) { cs[i], cs[j] = cs[j], cs[i] }
+
+// CurrencyStrength ranks currencies, strongest first, by their performance over the most recent
+// complete candle of granularity.  The score of a currency is the average log return of the
+// available instruments between any two of currencies, negated for the instruments in which it
+// is the quote currency.  See analytics.CurrencyStrength.  For example, use D and the eight major
+// currencies to rank the majors by their strength over the last day.
+func (c *Client) CurrencyStrength(granularity Granularity, currencies ...string) ([]CurrencyScore,
+	error) {
+
+	if len(currencies) < 2 {
+		return nil, &ValidationError{
+			Field:  "currencies",
+			Reason: "At least two currencies are required.",
+		}
+	}
+	wanted := make(map[string]bool, len(currencies))
+	for _, ccy := range currencies {
+		wanted[strings.ToUpper(strings.TrimSpace(ccy))] = true
+	}
+
+	names, err := c.instrumentNames()
+	if err != nil {
+		return nil, err
+	}
+	var returns []analytics.PairReturn
+	for _, name := range names {
+		base, quote := SplitInstrument(name)
+		if !wanted[base] || !wanted[quote] {
+			continue
+		}
+		candles, err := c.PollLastCompleteCandles(name, granularity, 2)
+		if err != nil {
+			return nil, err
+		}
+		rs := candles.Returns()
+		if len(rs) == 0 {
+			continue
+		}
+		returns = append(returns, analytics.PairReturn{
+			Base:   base,
+			Quote:  quote,
+			Return: rs[len(rs)-1].Log,
+		})
+	}
+
+	strength := analytics.CurrencyStrength(returns)
+	scores := make(currencyScores, 0, len(strength))
+	for ccy, score := range strength {
+		scores = append(scores, CurrencyScore{Currency: ccy, Score: score})
+	}
+	sort.Sort(scores)
+	return scores, nil
+}
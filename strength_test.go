@@ -0,0 +1,71 @@
+// Copyright 2014 Tjerk Santegoeds
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oanda_test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"gopkg.in/check.v1"
+
+	"github.com/santegoeds/oanda"
+)
+
+type StrengthSuite struct{}
+
+var _ = check.Suite(&StrengthSuite{})
+
+func (s *StrengthSuite) TestCurrencyStrength(c *check.C) {
+	closes := map[string][2]float64{
+		"EUR_USD": {1.0, 1.1},
+		"USD_JPY": {100, 100},
+		"EUR_JPY": {110, 121},
+		"GBP_USD": {1.5, 1.8},
+	}
+	tr := stubTransport{
+		StatusCode: 200,
+		BodyFunc: func(req *http.Request) string {
+			if req.URL.Path == "/v1/instruments" {
+				return `{"instruments": [{"instrument": "EUR_USD"}, {"instrument": "USD_JPY"},
+					{"instrument": "EUR_JPY"}, {"instrument": "GBP_USD"}]}`
+			}
+			instrument := req.URL.Query().Get("instrument")
+			cl := closes[instrument]
+			return fmt.Sprintf(`{"instrument": %q, "granularity": "D", "candles": [
+				{"time": "1400000000000000", "closeMid": %v, "complete": true},
+				{"time": "1400086400000000", "closeMid": %v, "complete": true},
+				{"time": "1400172800000000", "closeMid": 0, "complete": false}]}`,
+				instrument, cl[0], cl[1])
+		},
+	}
+	client := newStubClient(c, &tr)
+
+	_, err := client.CurrencyStrength(oanda.D, "EUR")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+
+	scores, err := client.CurrencyStrength(oanda.D, "eur", "usd", "jpy")
+	c.Assert(err, check.IsNil)
+	c.Assert(scores, check.HasLen, 3)
+	l := math.Log(1.1)
+	for i, expected := range []oanda.CurrencyScore{
+		{Currency: "EUR", Score: l},
+		{Currency: "JPY", Score: -l / 2},
+		{Currency: "USD", Score: -l / 2},
+	} {
+		c.Assert(scores[i].Currency, check.Equals, expected.Currency)
+		c.Assert(math.Abs(scores[i].Score-expected.Score) < 1e-9, check.Equals, true)
+	}
+}
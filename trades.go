@@ -15,12 +15,20 @@
 package oanda
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// awaitFillPollInterval is the interval at which NewTradeAndAwaitFill polls the transaction
+// history.
+const awaitFillPollInterval = time.Second
+
 type NewTradeArg interface {
 	applyNewTradeArg(url.Values)
 }
@@ -45,6 +53,58 @@ func (ts TrailingStop) applyNewTradeArg(v url.Values) {
 	optionalArgs(v).SetFloat("trailingStop", float64(ts))
 }
 
+// StopLossPips is an optional argument for Client method NewTrade() that places the stop loss the
+// specified number of pips away from the current price at which the trade would be executed;
+// below it for a buy and above it for a sell.
+type StopLossPips float64
+
+// TakeProfitPips is an optional argument for Client method NewTrade() that places the take profit
+// the specified number of pips away from the current price at which the trade would be executed;
+// above it for a buy and below it for a sell.
+type TakeProfitPips float64
+
+func (sl StopLossPips) applyNewTradeArg(v url.Values) {
+	optionalArgs(v).SetFloat("stopLossPips", float64(sl))
+}
+
+func (tp TakeProfitPips) applyNewTradeArg(v url.Values) {
+	optionalArgs(v).SetFloat("takeProfitPips", float64(tp))
+}
+
+// resolvePipDistances replaces the stopLossPips and takeProfitPips entries of data, which are not
+// understood by Oanda, with absolute stopLoss and takeProfit prices relative to price.
+func resolvePipDistances(data url.Values, side TradeSide, price float64, info InstrumentInfo) error {
+	if info.Pip <= 0 {
+		return fmt.Errorf("Unknown pip size for instrument %s", info.DisplayName)
+	}
+	dir := 1.0
+	if side == Sell {
+		dir = -1.0
+	}
+	for _, d := range []struct {
+		pipsKey, key string
+		dir          float64
+	}{
+		{"stopLossPips", "stopLoss", -dir},
+		{"takeProfitPips", "takeProfit", dir},
+	} {
+		s := data.Get(d.pipsKey)
+		if s == "" {
+			continue
+		}
+		data.Del(d.pipsKey)
+		pips, err := strconv.ParseFloat(s, 64)
+		if err != nil || pips <= 0 {
+			return &ValidationError{
+				Field:  d.pipsKey,
+				Reason: fmt.Sprintf("A positive number of pips is required instead of %s.", s),
+			}
+		}
+		data.Set(d.key, info.FormatPrice(price+d.dir*info.PipsToPrice(pips)))
+	}
+	return nil
+}
+
 type TradesArg interface {
 	applyTradesArg(url.Values)
 }
@@ -58,7 +118,7 @@ func (mi MaxId) applyTradesArg(v url.Values) {
 }
 
 func (i Instrument) applyTradesArg(v url.Values) {
-	v.Set("instrument", string(i))
+	v.Set("instrument", instrumentKey(string(i)))
 }
 
 func (ids Ids) applyTradesArg(v url.Values) {
@@ -103,12 +163,44 @@ func (t *Trade) String() string {
 
 type Trades []Trade
 
+// WeightedAvgPrice returns the unit-weighted average entry price and the net number of units of
+// the trades for the specified instrument.  Units of sell trades are counted as negative so that
+// a net short position results in a negative number of units.  The returned price is 0 if there
+// are no trades for the instrument.
+func (ts Trades) WeightedAvgPrice(instrument string) (price float64, units int) {
+	instrument = instrumentKey(instrument)
+
+	var total float64
+	for _, t := range ts {
+		if instrumentKey(t.Instrument) != instrument {
+			continue
+		}
+		n := t.Units
+		if TradeSide(t.Side) == Sell {
+			n = -n
+		}
+		total += float64(n) * t.Price
+		units += n
+	}
+	if units != 0 {
+		price = total / float64(units)
+	}
+	return price, units
+}
+
 // NewTrade submits a MarketOrder request to the Oanda servers. Supported OptionalArgs are
-// UpperBound(), LowerBound(), StopLoss(), TakeProfit() and TrailingStop().
+// UpperBound(), LowerBound(), StopLoss(), TakeProfit(), TrailingStop(), StopLossPips() and
+// TakeProfitPips().
+//
+// The returned OrderResponse reports whether the order opened a new trade, reduced an existing
+// trade and/or closed existing trades.
 func (c *Client) NewTrade(side TradeSide, units int, instrument string,
-	args ...NewTradeArg) (*Trade, error) {
+	args ...NewTradeArg) (*OrderResponse, error) {
 
-	instrument = strings.ToUpper(instrument)
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
 
 	data := url.Values{
 		"type":       {"market"},
@@ -121,35 +213,84 @@ func (c *Client) NewTrade(side TradeSide, units int, instrument string,
 		arg.applyNewTradeArg(data)
 	}
 
-	// FIXME: Replace this with a TradeCreatedResponse that mimics the structure that is actually
-	// returned.
-	t := &Trade{
-		Side:       string(side),
-		Units:      units,
-		Instrument: instrument,
+	// Bounds are checked against, and pip distances are relative to, the price at which the trade
+	// would currently be executed.
+	if err = validateBounds(data, 0); err != nil {
+		return nil, err
 	}
-
-	rspData := struct {
-		Instrument   string  `json:"instrument"`
-		Time         Time    `json:"time"`
-		Price        float64 `json:"price"`
-		TradeOpened  *Trade  `json:"tradeOpened"`
-		TradeReduced *Trade  `json:"tradeReduced"`
-	}{
-		TradeOpened:  t,
-		TradeReduced: t,
+	hasBounds := data.Get("lowerBound") != "" || data.Get("upperBound") != ""
+	hasPips := data.Get("stopLossPips") != "" || data.Get("takeProfitPips") != ""
+	if hasBounds || hasPips {
+		prices, err := c.PollPrices(instrument)
+		if err != nil {
+			return nil, err
+		}
+		price := prices[instrument].Ask
+		if side == Sell {
+			price = prices[instrument].Bid
+		}
+		if err = validateBounds(data, price); err != nil {
+			return nil, err
+		}
+		if hasPips {
+			info, err := c.InstrumentInfo(instrument)
+			if err != nil {
+				return nil, err
+			}
+			if err = resolvePipDistances(data, side, price, info); err != nil {
+				return nil, err
+			}
+		}
 	}
+	return c.submitOrder(data)
+}
+
+// NewTradeAndAwaitFill submits a MarketOrder request in the same way as NewTrade and then waits
+// until the MARKET_ORDER_CREATE transaction for the fill appears in the transaction history.  The
+// returned event holds the executed price and the details of the trade that was opened or
+// reduced.  The error returned by ctx.Err() is returned if ctx is done before the fill is found.
+func (c *Client) NewTradeAndAwaitFill(ctx context.Context, side TradeSide, units int,
+	instrument string, args ...NewTradeArg) (*TradeCreateEvent, error) {
 
-	urlStr := fmt.Sprintf("/v1/accounts/%d/orders", c.accountId)
-	if err := requestAndDecode(c, "POST", urlStr, data, &rspData); err != nil {
+	rsp, err := c.NewTrade(side, units, instrument, args...)
+	if err != nil {
 		return nil, err
 	}
 
-	t.Instrument = rspData.Instrument
-	t.Time = rspData.Time
-	t.Price = rspData.Price
+	isFill := func(evt Event) bool {
+		tce, ok := evt.(*TradeCreateEvent)
+		if !ok || tce.Time().UnixMicro() < rsp.Time.UnixMicro() {
+			return false
+		}
+		switch {
+		case rsp.TradeOpened != nil:
+			to := tce.TradeOpened()
+			return to != nil && to.TradeId() == rsp.TradeOpened.TradeId
+		case rsp.TradeReduced != nil:
+			tr := tce.TradeReduced()
+			return tr != nil && tr.TradeId() == rsp.TradeReduced.TradeId
+		}
+		return tce.Time() == rsp.Time
+	}
 
-	return t, nil
+	clock := c.clock()
+	for {
+		events, err := c.PollEvents(Instrument(rsp.Instrument))
+		if err != nil {
+			return nil, err
+		}
+		for _, evt := range events {
+			if isFill(evt) {
+				return evt.(*TradeCreateEvent), nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-clock.After(awaitFillPollInterval):
+		}
+	}
 }
 
 // Trade returns an open trade.
@@ -179,13 +320,15 @@ func (c *Client) Trades(args ...TradesArg) (Trades, error) {
 	u.RawQuery = q.Encode()
 	urlStr = u.String()
 
-	rspData := struct {
-		Trades Trades `json:"trades"`
-	}{}
-	if err = getAndDecode(c, urlStr, &rspData); err != nil {
+	var raw json.RawMessage
+	if err = getAndDecode(c, urlStr, &raw); err != nil {
 		return nil, err
 	}
-	return rspData.Trades, nil
+	var trades Trades
+	if err = decodeList(raw, "trades", &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
 }
 
 // ModifyTrade modifies an open trade.  Supported optional arguments are StopLoss(),
@@ -205,6 +348,475 @@ func (c *Client) ModifyTrade(tradeId Id, arg ModifyTradeArg, args ...ModifyTrade
 	return &t, nil
 }
 
+// maxTradesCount is the maximum number of trades that Oanda returns for a single request.
+const maxTradesCount = 500
+
+// allTrades returns all open trades that match args, which must not include Count or MaxId, by
+// requesting as many pages of trades as needed.
+func (c *Client) allTrades(args ...TradesArg) (Trades, error) {
+	var trades Trades
+	pageArgs := append([]TradesArg{Count(maxTradesCount)}, args...)
+	for {
+		page, err := c.Trades(pageArgs...)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, page...)
+		if len(page) < maxTradesCount {
+			return trades, nil
+		}
+		pageArgs = append([]TradesArg{Count(maxTradesCount),
+			MaxId(page[len(page)-1].TradeId - 1)}, args...)
+	}
+}
+
+// ModifyTradeResult holds the outcome of the modification of a single trade.  Err is nil if the
+// trade was modified successfully.
+type ModifyTradeResult struct {
+	TradeId Id
+	Trade   *Trade
+	Err     error
+}
+
+// ModifyTradesError is returned when one or more trades could not be modified.
+type ModifyTradesError struct {
+	Failed []ModifyTradeResult
+}
+
+func (e *ModifyTradesError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		msgs[i] = fmt.Sprintf("trade %d: %v", r.TradeId, r.Err)
+	}
+	return fmt.Sprintf("Failed to modify %d trade(s): %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+// ModifyTradesForInstrument applies the same modification to all open trades for instrument.
+// Supported arguments are StopLoss(), TakeProfit() and TrailingStop().  The result of every
+// modification is returned.  If any of the trades could not be modified a *ModifyTradesError that
+// holds the failed modifications is returned as well.
+func (c *Client) ModifyTradesForInstrument(instrument string,
+	args ...ModifyTradeArg) ([]ModifyTradeResult, error) {
+
+	if len(args) == 0 {
+		return nil, &ValidationError{
+			Field:  "args",
+			Reason: "At least one of StopLoss, TakeProfit or TrailingStop is required.",
+		}
+	}
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
+
+	trades, err := c.allTrades(Instrument(instrument))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ModifyTradeResult, len(trades))
+	modifyErr := ModifyTradesError{}
+	for i, t := range trades {
+		mt, err := c.ModifyTrade(t.TradeId, args[0], args[1:]...)
+		results[i] = ModifyTradeResult{t.TradeId, mt, err}
+		if err != nil {
+			modifyErr.Failed = append(modifyErr.Failed, results[i])
+		}
+	}
+	if len(modifyErr.Failed) > 0 {
+		return results, &modifyErr
+	}
+	return results, nil
+}
+
+// TradeStatus holds the unrealized state of an open trade.
+type TradeStatus struct {
+	Trade
+	// CurrentPrice is the price at which the trade would currently be closed.  The entry price
+	// of the trade is Trade.Price.
+	CurrentPrice float64
+	// PlPips is the unrealized profit or loss in pips.
+	PlPips float64
+	// Pl is the unrealized profit or loss in the home currency of the account.
+	Pl float64
+	// StopLossPips, TakeProfitPips and TrailingStopPips are the distances in pips between
+	// CurrentPrice and the respective order.  A distance is 0 if the trade has no such order.
+	StopLossPips     float64
+	TakeProfitPips   float64
+	TrailingStopPips float64
+}
+
+// String implements the fmt.Stringer interface.
+func (ts TradeStatus) String() string {
+	return fmt.Sprintf("TradeStatus{TradeId: %d, Instrument: %s, CurrentPrice: %v, PlPips: %.1f, "+
+		"Pl: %v}", ts.TradeId, ts.Instrument, ts.CurrentPrice, ts.PlPips, ts.Pl)
+}
+
+// NearStop returns true if the stop loss or trailing stop of the trade is within pips of the
+// current price.
+func (ts TradeStatus) NearStop(pips float64) bool {
+	return (ts.Trade.StopLoss != 0 && ts.StopLossPips <= pips) ||
+		(ts.Trade.TrailingAmount != 0 && ts.TrailingStopPips <= pips)
+}
+
+// TradeStatus returns the trade with the specified id together with its unrealized profit or loss
+// and the distances to its stop loss, take profit and trailing stop.
+func (c *Client) TradeStatus(tradeId Id) (*TradeStatus, error) {
+	t, err := c.Trade(tradeId)
+	if err != nil {
+		return nil, err
+	}
+	cc, err := c.ConversionContext()
+	if err != nil {
+		return nil, err
+	}
+	pip, err := cc.Pip(t.Instrument)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := c.PollPrices(t.Instrument)
+	if err != nil {
+		return nil, err
+	}
+	tick, ok := prices[t.Instrument]
+	if !ok {
+		return nil, fmt.Errorf("No price for instrument %s", t.Instrument)
+	}
+
+	// dir is 1 for a long trade and -1 for a short trade so that positive distances are in the
+	// direction of profit.
+	ts := TradeStatus{Trade: *t, CurrentPrice: tick.Bid}
+	dir := 1.0
+	if TradeSide(t.Side) == Sell {
+		ts.CurrentPrice, dir = tick.Ask, -1.0
+	}
+
+	ts.PlPips = dir * (ts.CurrentPrice - t.Price) / pip
+	ts.Pl, err = cc.Pl(t.Instrument, TradeSide(t.Side), t.Units, t.Price, ts.CurrentPrice)
+	if err != nil {
+		return nil, err
+	}
+	if t.StopLoss != 0 {
+		ts.StopLossPips = dir * (ts.CurrentPrice - t.StopLoss) / pip
+	}
+	if t.TakeProfit != 0 {
+		ts.TakeProfitPips = dir * (t.TakeProfit - ts.CurrentPrice) / pip
+	}
+	if t.TrailingAmount != 0 {
+		ts.TrailingStopPips = dir * (ts.CurrentPrice - t.TrailingAmount) / pip
+	}
+	return &ts, nil
+}
+
+// RequiredMargin returns the margin in home currency that a trade of units of instrument would
+// consume at the current price.  The margin rate is the larger of the margin rate of the
+// instrument and that of the selected account.
+func (c *Client) RequiredMargin(instrument string, units int) (float64, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return 0, err
+	}
+	margin, _, _, err := c.requiredMargin(instrument, units)
+	return margin, err
+}
+
+// requiredMargin returns the margin that a trade of units of instrument would consume together
+// with the account and instrument information from which it was calculated.
+func (c *Client) requiredMargin(instrument string, units int) (float64, *Account, *InstrumentInfo, error) {
+	acc, err := c.Account(c.accountId)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	instruments, err := c.Instruments([]string{instrument},
+		[]InstrumentField{MarginRateField, MaxTradeUnitsField})
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	info, ok := instruments[instrument]
+	if !ok {
+		return 0, nil, nil, fmt.Errorf("Unknown instrument %s", instrument)
+	}
+	cc, err := c.ConversionContext()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if units < 0 {
+		units = -units
+	}
+	exposure, err := cc.Exposure(instrument, units)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return exposure * math.Max(info.MarginRate, acc.MarginRate), acc, &info, nil
+}
+
+// UnitsForMarginFraction returns the number of units of instrument that a new trade can have
+// such that it consumes at most fraction (0 < fraction <= 1) of the margin available in the
+// selected account.  The result is capped at the maximum trade size of the instrument.
+func (c *Client) UnitsForMarginFraction(instrument string, fraction float64) (int, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return 0, err
+	}
+	if fraction <= 0 || fraction > 1 {
+		return 0, &ValidationError{
+			Field:  "fraction",
+			Reason: fmt.Sprintf("Margin fraction %v is not in the range (0, 1]", fraction),
+		}
+	}
+	unitMargin, acc, info, err := c.requiredMargin(instrument, 1)
+	if err != nil {
+		return 0, err
+	}
+	if unitMargin <= 0 {
+		return 0, fmt.Errorf("No margin requirement known for instrument %s", instrument)
+	}
+	units := int(math.Floor(fraction * acc.MarginAvailable / unitMargin))
+	if units < 0 {
+		units = 0
+	}
+	if info.MaxTradeUnits > 0 && units > info.MaxTradeUnits {
+		units = info.MaxTradeUnits
+	}
+	return units, nil
+}
+
+// TradePreview holds the margin that a prospective trade would consume.
+type TradePreview struct {
+	Instrument      string
+	Units           int
+	RequiredMargin  float64
+	MarginAvailable float64
+}
+
+// String implements the fmt.Stringer interface.
+func (tp TradePreview) String() string {
+	return fmt.Sprintf("TradePreview{Instrument: %s, Units: %d, RequiredMargin: %v, "+
+		"MarginAvailable: %v}", tp.Instrument, tp.Units, tp.RequiredMargin, tp.MarginAvailable)
+}
+
+// HasSufficientMargin returns true if the available margin covers the required margin.
+func (tp TradePreview) HasSufficientMargin() bool {
+	return tp.RequiredMargin <= tp.MarginAvailable
+}
+
+// PreviewTrade returns the margin that a trade of units of instrument would consume and the
+// margin that is available in the selected account.
+func (c *Client) PreviewTrade(instrument string, units int) (*TradePreview, error) {
+	instrument, err := NormalizeInstrument(instrument)
+	if err != nil {
+		return nil, err
+	}
+	margin, acc, _, err := c.requiredMargin(instrument, units)
+	if err != nil {
+		return nil, err
+	}
+	tp := TradePreview{
+		Instrument:      instrument,
+		Units:           units,
+		RequiredMargin:  margin,
+		MarginAvailable: acc.MarginAvailable,
+	}
+	return &tp, nil
+}
+
+// ClosedTradePl searches the transaction history for the event that closed the trade with the
+// specified id, either a close of the trade such as STOP_LOSS_FILLED or an opposing order that
+// reduced the remaining units of the trade to zero.  The result holds the realized profit or loss
+// and interest of all the units of the trade, including those of earlier reductions; its
+// CloseReason is the reason why the trade was closed.
+func (c *Client) ClosedTradePl(tradeId Id) (*ClosedTrade, error) {
+	events, err := c.eventHistory(time.Time{}, MinId(tradeId))
+	if err != nil {
+		return nil, err
+	}
+	entries := c.newTradeEntries()
+	units, pl, interest := 0, 0.0, 0.0
+	for _, evt := range events {
+		entries.add(evt)
+		id, exit, ok := tradeExit(evt)
+		if !ok || id != tradeId {
+			continue
+		}
+		open, err := entries.lookup(tradeId)
+		if err != nil {
+			return nil, err
+		}
+		units, pl, interest = units+exit.Units, pl+exit.Pl, interest+exit.Interest
+		if _, isClose := evt.(*TradeCloseEvent); !isClose && units < open.Units {
+			continue
+		}
+		ct := open.closedBy(exit)
+		ct.Units, ct.Pl, ct.Interest = units, pl, interest
+		return &ct, nil
+	}
+	return nil, fmt.Errorf("No close event found for trade %d", tradeId)
+}
+
+// ClosedTrade is a trade, or the part of a trade, that was closed as reconstructed from the
+// transaction history.
+type ClosedTrade struct {
+	TradeId    Id
+	Instrument string
+	Side       string
+	// Units is the number of units that were closed, which is less than the size of the trade
+	// if the trade was reduced.
+	Units      int
+	EntryPrice float64
+	EntryTime  Time
+	ExitPrice  float64
+	ExitTime   Time
+	Pl         float64
+	Interest   float64
+	// CloseReason is the type of the transaction that closed the trade; e.g. STOP_LOSS_FILLED.
+	CloseReason string
+}
+
+// String implements the fmt.Stringer interface.
+func (ct ClosedTrade) String() string {
+	return fmt.Sprintf("ClosedTrade{TradeId: %d, Instrument: %s, Side: %s, Units: %d, "+
+		"EntryPrice: %v, ExitPrice: %v, Pl: %v, CloseReason: %s}", ct.TradeId, ct.Instrument,
+		ct.Side, ct.Units, ct.EntryPrice, ct.ExitPrice, ct.Pl, ct.CloseReason)
+}
+
+// Duration returns the time for which the trade was open.
+func (ct ClosedTrade) Duration() time.Duration {
+	return ct.ExitTime.Time().Sub(ct.EntryTime.Time())
+}
+
+// ClosedTrades returns the trades that were closed or reduced between start and end, inclusive,
+// in the order in which they were closed.  Trades that were opened before start are looked up
+// in the transaction history.
+func (c *Client) ClosedTrades(start, end time.Time) ([]ClosedTrade, error) {
+	events, err := c.eventHistory(start)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := c.newTradeEntries()
+	closed := []ClosedTrade{}
+	for _, evt := range events {
+		if evt.Time().Time().After(end) {
+			break
+		}
+		entries.add(evt)
+		tradeId, exit, ok := tradeExit(evt)
+		if !ok {
+			continue
+		}
+		open, err := entries.lookup(tradeId)
+		if err != nil {
+			return nil, err
+		}
+		closed = append(closed, open.closedBy(exit))
+	}
+	return closed, nil
+}
+
+// closedBy returns the trade of which ct holds the entry, closed as described by exit.
+func (ct ClosedTrade) closedBy(exit ClosedTrade) ClosedTrade {
+	ct.Units, ct.Pl, ct.Interest = exit.Units, exit.Pl, exit.Interest
+	ct.ExitPrice, ct.ExitTime, ct.CloseReason = exit.ExitPrice, exit.ExitTime, exit.CloseReason
+	return ct
+}
+
+// tradeEntries holds the entries of trades, as returned by tradeEntry, by trade id.
+type tradeEntries struct {
+	c       *Client
+	entries map[Id]ClosedTrade
+}
+
+func (c *Client) newTradeEntries() *tradeEntries {
+	return &tradeEntries{c: c, entries: make(map[Id]ClosedTrade)}
+}
+
+// add records the entry of the trade that evt opened, if any.
+func (te *tradeEntries) add(evt Event) {
+	if ct, ok := tradeEntry(evt); ok {
+		te.entries[ct.TradeId] = ct
+	}
+}
+
+// lookup returns the entry of a trade.  Trades that were not added are looked up in the
+// transaction history.
+func (te *tradeEntries) lookup(tradeId Id) (ClosedTrade, error) {
+	if ct, ok := te.entries[tradeId]; ok {
+		return ct, nil
+	}
+	evt, err := te.c.PollEvent(tradeId)
+	if err != nil {
+		return ClosedTrade{}, err
+	}
+	ct, ok := tradeEntry(evt)
+	if !ok {
+		return ClosedTrade{}, fmt.Errorf("No open event found for trade %d", tradeId)
+	}
+	te.entries[tradeId] = ct
+	return ct, nil
+}
+
+// tradeExit returns the id of the trade that evt closed or reduced, together with the closed
+// units, profit or loss, interest, exit price and time and close reason.  The result is false if
+// evt did not close or reduce a trade.
+func tradeExit(evt Event) (Id, ClosedTrade, bool) {
+	ct := ClosedTrade{ExitTime: evt.Time(), CloseReason: evt.Type()}
+	var tradeId Id
+	switch e := evt.(type) {
+	case *TradeCloseEvent:
+		tradeId, ct.ExitPrice = e.TradeId(), e.Price()
+		ct.Units, ct.Pl, ct.Interest = e.Units(), e.Pl(), e.Interest()
+	case *TradeCreateEvent:
+		tr := e.TradeReduced()
+		if tr == nil {
+			return 0, ct, false
+		}
+		tradeId, ct.ExitPrice = tr.TradeId(), e.Price()
+		ct.Units, ct.Pl, ct.Interest = tr.Units(), tr.Pl(), tr.Interest()
+	case *OrderFilledEvent:
+		tr := e.TradeReduced()
+		if tr == nil {
+			return 0, ct, false
+		}
+		tradeId, ct.ExitPrice = tr.TradeId(), e.Price()
+		ct.Units, ct.Pl, ct.Interest = tr.Units(), tr.Pl(), tr.Interest()
+	default:
+		return 0, ct, false
+	}
+	ct.TradeId = tradeId
+	return tradeId, ct, true
+}
+
+// tradeEntry returns the trade id, instrument, side, units and entry price and time of the trade
+// that evt opened.  The result is false if evt did not open a trade.
+func tradeEntry(evt Event) (ClosedTrade, bool) {
+	ct := ClosedTrade{EntryTime: evt.Time()}
+	switch e := evt.(type) {
+	case *TradeCreateEvent:
+		to := e.TradeOpened()
+		if to == nil {
+			return ct, false
+		}
+		ct.TradeId, ct.Instrument, ct.Side, ct.EntryPrice = to.TradeId(), e.Instrument(),
+			e.Side(), e.Price()
+		ct.Units = to.Units()
+	case *OrderFilledEvent:
+		to := e.TradeOpened()
+		if to == nil {
+			return ct, false
+		}
+		ct.TradeId, ct.Instrument, ct.Side, ct.EntryPrice = to.TradeId(), e.Instrument(),
+			e.Side(), e.Price()
+		ct.Units = to.Units()
+	case *MigrateTradeOpenEvent:
+		ct.TradeId, ct.Instrument, ct.Side, ct.EntryPrice = e.TranId(), e.Instrument(),
+			e.Side(), e.Price()
+		ct.Units = e.Units()
+	default:
+		return ct, false
+	}
+	return ct, true
+}
+
 type CloseTradeResponse struct {
 	TransactionId Id      `json:"id"`
 	Price         float64 `json:"price"`
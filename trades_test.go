@@ -15,6 +15,10 @@
 package oanda_test
 
 import (
+	"context"
+	"math"
+	"time"
+
 	"github.com/santegoeds/oanda"
 
 	check "gopkg.in/check.v1"
@@ -26,15 +30,119 @@ type TestTradeSuite struct {
 
 var _ = check.Suite(&TestTradeSuite{})
 
+type TradesSuite struct{}
+
+var _ = check.Suite(&TradesSuite{})
+
+func (s *TradesSuite) TestWeightedAvgPrice(c *check.C) {
+	trades := oanda.Trades{
+		{Instrument: "EUR_USD", Side: "buy", Units: 1, Price: 1.1},
+		{Instrument: "EUR_USD", Side: "buy", Units: 3, Price: 1.3},
+		{Instrument: "GBP_USD", Side: "buy", Units: 5, Price: 1.5},
+	}
+	price, units := trades.WeightedAvgPrice("eur_usd")
+	c.Assert(units, check.Equals, 4)
+	c.Assert(math.Abs(price-1.25) < 1e-9, check.Equals, true)
+
+	trades = oanda.Trades{
+		{Instrument: "EUR_USD", Side: "sell", Units: 2, Price: 1.2},
+		{Instrument: "EUR_USD", Side: "sell", Units: 2, Price: 1.4},
+	}
+	price, units = trades.WeightedAvgPrice("EUR_USD")
+	c.Assert(units, check.Equals, -4)
+	c.Assert(math.Abs(price-1.3) < 1e-9, check.Equals, true)
+
+	price, units = trades.WeightedAvgPrice("USD_JPY")
+	c.Assert(units, check.Equals, 0)
+	c.Assert(price, check.Equals, 0.0)
+}
+
+func (s *TradesSuite) TestTradeStatus(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/trades/5": `{"id": 5, "units": 10000, "instrument": "EUR_USD",
+				"side": "buy", "price": 1.1000, "trailingStop": 5, "trailingAmount": 1.1005}`,
+			"/v1/accounts/1":  `{"accountId": 1, "accountCurrency": "USD"}`,
+			"/v1/instruments": `{"instruments": [{"instrument": "EUR_USD", "pip": "0.0001"}]}`,
+			"/v1/prices": `{"prices": [
+				{"instrument": "EUR_USD", "bid": 1.1008, "ask": 1.1010}
+			]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	ts, err := client.TradeStatus(5)
+	c.Assert(err, check.IsNil)
+	c.Log(ts)
+	c.Assert(ts.CurrentPrice, check.Equals, 1.1008)
+	c.Assert(ts.Price, check.Equals, 1.1)
+	c.Assert(math.Abs(ts.PlPips-8) < 1e-6, check.Equals, true)
+	c.Assert(math.Abs(ts.Pl-8) < 1e-6, check.Equals, true)
+	c.Assert(math.Abs(ts.TrailingStopPips-3) < 1e-6, check.Equals, true)
+	c.Assert(ts.StopLossPips, check.Equals, 0.0)
+	c.Assert(ts.NearStop(3.5), check.Equals, true)
+	c.Assert(ts.NearStop(2.5), check.Equals, false)
+}
+
+func (s *TradesSuite) TestClosedTradePl(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/transactions": `{"transactions": [
+				{"id": 15, "type": "ORDER_FILLED", "time": "1400001500000000",
+				 "instrument": "EUR_USD", "units": 6, "side": "sell", "price": 1.15,
+				 "tradeReduced": {"id": 13, "units": 6, "pl": 3, "interest": 0.2}},
+				{"id": 14, "type": "MARKET_ORDER_CREATE", "time": "1400001400000000",
+				 "instrument": "EUR_USD", "units": 4, "side": "sell", "price": 1.12,
+				 "tradeReduced": {"id": 13, "units": 4, "pl": 1, "interest": 0.1}},
+				{"id": 13, "type": "MARKET_ORDER_CREATE", "time": "1400001300000000",
+				 "instrument": "EUR_USD", "units": 10, "side": "buy", "price": 1.1,
+				 "tradeOpened": {"id": 13, "units": 10}},
+				{"id": 12, "type": "STOP_LOSS_FILLED", "time": "1400001200000000", "tradeId": 10,
+				 "units": 5, "price": 1.05, "pl": -1.5, "interest": 0.01},
+				{"id": 11, "type": "TRADE_CLOSE", "tradeId": 9, "pl": 2.5}]}`,
+			"/v1/accounts/1/transactions/10": `{"id": 10, "type": "MARKET_ORDER_CREATE",
+				"time": "1400001000000000", "instrument": "EUR_USD", "units": 5, "side": "buy",
+				"price": 1.1, "tradeOpened": {"id": 10, "units": 5}}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	ct, err := client.ClosedTradePl(10)
+	c.Assert(err, check.IsNil)
+	c.Assert(ct.CloseReason, check.Equals, "STOP_LOSS_FILLED")
+	c.Assert(ct.Pl, check.Equals, -1.5)
+	c.Assert(ct.Interest, check.Equals, 0.01)
+	c.Assert(ct.EntryPrice, check.Equals, 1.1)
+	c.Assert(tr.Requests[0].URL.Query().Get("minId"), check.Equals, "10")
+
+	// Trade 13 is closed by two opposing orders.
+	ct, err = client.ClosedTradePl(13)
+	c.Assert(err, check.IsNil)
+	c.Assert(ct.CloseReason, check.Equals, "ORDER_FILLED")
+	c.Assert(ct.Units, check.Equals, 10)
+	c.Assert(ct.Pl, check.Equals, 4.0)
+	c.Assert(math.Abs(ct.Interest-0.3) < 1e-9, check.Equals, true)
+	c.Assert(ct.ExitPrice, check.Equals, 1.15)
+
+	_, err = client.ClosedTradePl(8)
+	c.Assert(err, check.ErrorMatches, "No close event found for trade 8")
+}
+
 func (ts *TestTradeSuite) SetUpSuite(c *check.C) {
 	ts.OandaSuite.SetUpSuite(c)
 	ts.SetUpAccount(c)
 }
 
 func (ts *TestTradeSuite) TestTradeApi(c *check.C) {
-	t, err := ts.Client.NewTrade(oanda.Buy, 2, "eur_usd", oanda.StopLoss(0.5), oanda.TakeProfit(3.0))
+	rsp, err := ts.Client.NewTrade(oanda.Buy, 2, "eur_usd", oanda.StopLoss(0.5), oanda.TakeProfit(3.0))
 	c.Assert(err, check.IsNil)
-	c.Log(t)
+	c.Log(rsp)
+	t := rsp.TradeOpened
+	c.Assert(t, check.NotNil)
 	c.Assert(t.TradeId, check.Not(check.Equals), 0)
 	c.Assert(t.Price, check.Not(check.Equals), 0.0)
 	c.Assert(t.Instrument, check.Equals, "EUR_USD")
@@ -73,11 +181,352 @@ func (ts *TestTradeSuite) TestTradeApi(c *check.C) {
 	c.Assert(trades[0].TrailingStop, check.Equals, t.TrailingStop)
 	c.Assert(trades[0].Time, check.Equals, t.Time)
 
-	rsp, err := ts.Client.CloseTrade(t.TradeId)
+	ctr, err := ts.Client.CloseTrade(t.TradeId)
 	c.Assert(err, check.IsNil)
-	c.Log(rsp)
+	c.Log(ctr)
 
 	trades, err = ts.Client.Trades()
 	c.Assert(err, check.IsNil)
 	c.Assert(trades, check.HasLen, 0)
 }
+
+func (ts *TestTradeSuite) TestNewTradeAndAwaitFill(c *check.C) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	evt, err := ts.Client.NewTradeAndAwaitFill(ctx, oanda.Buy, 1, "eur_usd")
+	c.Assert(err, check.IsNil)
+	c.Log(evt)
+	c.Assert(evt.Instrument(), check.Equals, "EUR_USD")
+	c.Assert(evt.Side(), check.Equals, string(oanda.Buy))
+	c.Assert(evt.Price() > 0.0, check.Equals, true)
+	c.Assert(evt.TradeOpened(), check.NotNil)
+
+	_, err = ts.Client.CloseTrade(evt.TradeOpened().TradeId())
+	c.Assert(err, check.IsNil)
+}
+
+func (ts *TestTradeSuite) TestTradesWeightedAvgPrice(c *check.C) {
+	for i := 0; i < 3; i++ {
+		_, err := ts.Client.NewTrade(oanda.Buy, i+1, "eur_usd")
+		c.Assert(err, check.IsNil)
+	}
+
+	trades, err := ts.Client.Trades(oanda.Instrument("EUR_USD"))
+	c.Assert(err, check.IsNil)
+	price, units := trades.WeightedAvgPrice("eur_usd")
+
+	p, err := ts.Client.Position("eur_usd")
+	c.Assert(err, check.IsNil)
+	c.Log(p)
+	c.Assert(units, check.Equals, p.Units)
+	c.Assert(math.Abs(price-p.AvgPrice) < 1e-5, check.Equals, true)
+
+	_, err = ts.Client.ClosePosition("eur_usd")
+	c.Assert(err, check.IsNil)
+}
+
+func (s *TradesSuite) TestNewTradeNormalizesInstrument(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"instrument": "EUR_USD", "time": "1400000000000000", "price": 1.1,
+			"tradeOpened": {"id": 1, "units": 1, "side": "buy"}}`,
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	rsp, err := client.NewTrade(oanda.Buy, 1, "eur/usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(rsp.Instrument, check.Equals, "EUR_USD")
+	c.Assert(tr.Requests, check.HasLen, 1)
+	c.Assert(tr.Requests[0].FormValue("instrument"), check.Equals, "EUR_USD")
+
+	_, err = client.NewTrade(oanda.Buy, 1, "eur.usd")
+	c.Assert(err, check.NotNil)
+	c.Assert(tr.Requests, check.HasLen, 1)
+}
+
+func (s *TradesSuite) TestNewTradeBounds(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "bid": 1.1, "ask": 1.2}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err := client.NewTrade(oanda.Buy, 1, "eur_usd", oanda.UpperBound(0.5),
+		oanda.LowerBound(1.5))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	_, err = client.NewTrade(oanda.Buy, 1, "eur_usd", oanda.UpperBound(1.15))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 1)
+
+	_, err = client.NewTrade(oanda.Sell, 1, "eur_usd", oanda.UpperBound(1.15),
+		oanda.LowerBound(1.05))
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests, check.HasLen, 3)
+	c.Assert(tr.Requests[2].URL.Path, check.Equals, "/v1/accounts/1/orders")
+}
+
+func (s *TradesSuite) TestNewTradePipDistances(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "bid": 1.1, "ask": 1.1002}]}`,
+			"/v1/instruments": `{"instruments": [
+				{"instrument": "EUR_USD", "pip": "0.0001", "precision": "0.00001"}]}`,
+		},
+		Body: `{"instrument": "EUR_USD"}`,
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err := client.NewTrade(oanda.Buy, 1, "eur_usd", oanda.StopLossPips(20),
+		oanda.TakeProfitPips(40))
+	c.Assert(err, check.IsNil)
+	req := tr.Requests[len(tr.Requests)-1]
+	c.Assert(req.URL.Path, check.Equals, "/v1/accounts/1/orders")
+	c.Assert(req.FormValue("stopLoss"), check.Equals, "1.09820")
+	c.Assert(req.FormValue("takeProfit"), check.Equals, "1.10420")
+	c.Assert(req.FormValue("stopLossPips"), check.Equals, "")
+	c.Assert(req.FormValue("takeProfitPips"), check.Equals, "")
+
+	_, err = client.NewTrade(oanda.Sell, 1, "eur_usd", oanda.StopLossPips(20))
+	c.Assert(err, check.IsNil)
+	req = tr.Requests[len(tr.Requests)-1]
+	c.Assert(req.FormValue("stopLoss"), check.Equals, "1.10200")
+	c.Assert(req.FormValue("takeProfit"), check.Equals, "")
+
+	n := len(tr.Requests)
+	_, err = client.NewTrade(oanda.Sell, 1, "eur_usd", oanda.TakeProfitPips(-5))
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(err.(*oanda.ValidationError).Field, check.Equals, "takeProfitPips")
+	for _, req := range tr.Requests[n:] {
+		c.Assert(req.URL.Path, check.Not(check.Equals), "/v1/accounts/1/orders")
+	}
+}
+
+func (s *TradesSuite) TestClosedTrades(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1/transactions": `{"transactions": [
+				{"id": 6, "type": "TRADE_CLOSE", "time": "1400000900000000", "tradeId": 2,
+				 "instrument": "EUR_USD", "units": 10, "side": "buy", "price": 1.3, "pl": 20},
+				{"id": 5, "type": "STOP_LOSS_FILLED", "time": "1400000500000000", "tradeId": 2,
+				 "instrument": "EUR_USD", "units": 10, "side": "buy", "price": 1.05, "pl": -5},
+				{"id": 4, "type": "MARKET_ORDER_CREATE", "time": "1400000400000000",
+				 "instrument": "EUR_USD", "units": 5, "side": "sell", "price": 1.2,
+				 "tradeReduced": {"id": 1, "units": 5, "pl": 1, "interest": 0.1}},
+				{"id": 2, "type": "MARKET_ORDER_CREATE", "time": "1400000200000000",
+				 "instrument": "EUR_USD", "units": 10, "side": "buy", "price": 1.1,
+				 "tradeOpened": {"id": 2, "units": 10}}]}`,
+			"/v1/accounts/1/transactions/1": `{"id": 1, "type": "MARKET_ORDER_CREATE",
+				"time": "1400000000000000", "instrument": "EUR_USD", "units": 10, "side": "buy",
+				"price": 1.0, "tradeOpened": {"id": 1, "units": 10}}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	closed, err := client.ClosedTrades(time.Unix(1400000100, 0), time.Unix(1400000600, 0))
+	c.Assert(err, check.IsNil)
+	c.Assert(closed, check.HasLen, 2)
+
+	c.Assert(closed[0].TradeId, check.Equals, oanda.Id(1))
+	c.Assert(closed[0].Side, check.Equals, "buy")
+	c.Assert(closed[0].Units, check.Equals, 5)
+	c.Assert(closed[0].EntryPrice, check.Equals, 1.0)
+	c.Assert(closed[0].ExitPrice, check.Equals, 1.2)
+	c.Assert(closed[0].Pl, check.Equals, 1.0)
+	c.Assert(closed[0].Interest, check.Equals, 0.1)
+	c.Assert(closed[0].CloseReason, check.Equals, "MARKET_ORDER_CREATE")
+	c.Assert(closed[0].Duration(), check.Equals, 400*time.Second)
+
+	c.Assert(closed[1].TradeId, check.Equals, oanda.Id(2))
+	c.Assert(closed[1].EntryPrice, check.Equals, 1.1)
+	c.Assert(closed[1].ExitPrice, check.Equals, 1.05)
+	c.Assert(closed[1].Pl, check.Equals, -5.0)
+	c.Assert(closed[1].CloseReason, check.Equals, "STOP_LOSS_FILLED")
+	c.Assert(closed[1].Duration(), check.Equals, 300*time.Second)
+}
+
+func (s *TradesSuite) TestNewTradeReducesTrade(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"instrument": "EUR_USD", "time": "1400000000000000", "price": 1.1,
+			"tradeOpened": {}, "tradesClosed": [{"id": 1, "units": 2, "side": "sell"}],
+			"tradeReduced": {"id": 2, "units": 3, "pl": 1.5, "interest": 0.1}}`,
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	rsp, err := client.NewTrade(oanda.Buy, 5, "EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(rsp.TradeOpened, check.IsNil)
+	c.Assert(rsp.TradeReduced, check.DeepEquals, &oanda.TradeDetail{
+		TradeId: 2, Units: 3, Pl: 1.5, Interest: 0.1})
+	c.Assert(rsp.TradesClosed, check.DeepEquals, []oanda.TradeDetail{
+		{TradeId: 1, Units: 2, Side: "sell"}})
+	c.Assert(rsp.OrderOpened, check.IsNil)
+	c.Assert(rsp.RequestedUnits, check.Equals, 5)
+	c.Assert(rsp.ReducedUnits(), check.Equals, 5)
+	c.Assert(rsp.FilledUnits(), check.Equals, 5)
+	c.Assert(rsp.FillStatus(), check.Equals, oanda.Filled)
+}
+
+func (s *TradesSuite) TestNewTradePartialFill(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Body: `{"instrument": "EUR_USD", "time": "1400000000000000", "price": 1.1,
+			"tradeOpened": {"id": 3, "units": 6000, "side": "buy"},
+			"tradeReduced": {"id": 2, "units": 2000}}`,
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	rsp, err := client.NewTrade(oanda.Buy, 10000, "EUR_USD")
+	c.Assert(err, check.IsNil)
+	c.Assert(rsp.RequestedUnits, check.Equals, 10000)
+	c.Assert(rsp.ReducedUnits(), check.Equals, 2000)
+	c.Assert(rsp.FilledUnits(), check.Equals, 8000)
+	c.Assert(rsp.FillStatus(), check.Equals, oanda.PartiallyFilled)
+
+	c.Assert(oanda.OrderResponse{RequestedUnits: 10}.FillStatus(), check.Equals, oanda.Unfilled)
+}
+
+func (s *TradesSuite) TestRequiredMargin(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1": `{"accountId": 1, "accountCurrency": "USD", "marginRate": 0.02,
+				"marginAvail": 300}`,
+			"/v1/instruments": `{"instruments": [
+				{"instrument": "EUR_USD", "pip": "0.0001", "marginRate": 0.03}]}`,
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "bid": 1.1, "ask": 1.2}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	margin, err := client.RequiredMargin("eur_usd", 10000)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(margin-345) < 1e-9, check.Equals, true)
+
+	tp, err := client.PreviewTrade("EUR_USD", -20000)
+	c.Assert(err, check.IsNil)
+	c.Assert(math.Abs(tp.RequiredMargin-690) < 1e-9, check.Equals, true)
+	c.Assert(tp.MarginAvailable, check.Equals, 300.0)
+	c.Assert(tp.HasSufficientMargin(), check.Equals, false)
+}
+
+func (s *TradesSuite) TestUnitsForMarginFraction(c *check.C) {
+	tr := stubTransport{
+		StatusCode: 200,
+		Bodies: map[string]string{
+			"/v1/accounts/1": `{"accountId": 1, "accountCurrency": "USD", "marginRate": 0.02,
+				"marginAvail": 3450}`,
+			"/v1/instruments": `{"instruments": [
+				{"instrument": "EUR_USD", "pip": "0.0001", "marginRate": 0.03,
+				 "maxTradeUnits": 50000}]}`,
+			"/v1/prices": `{"prices": [{"instrument": "EUR_USD", "bid": 1.1, "ask": 1.2}]}`,
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	units, err := client.UnitsForMarginFraction("EUR_USD", 0.1)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.Equals, 10000)
+
+	units, err = client.UnitsForMarginFraction("eur/usd", 1)
+	c.Assert(err, check.IsNil)
+	c.Assert(units, check.Equals, 50000)
+
+	_, err = client.UnitsForMarginFraction("EUR_USD", 1.5)
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+}
+
+func (s *TradesSuite) TestModifyTradesForInstrument(c *check.C) {
+	tr := stubTransport{
+		Script: []stubResponse{
+			{StatusCode: 200, Body: `{"trades": [
+				{"id": 2, "instrument": "EUR_USD", "units": 1, "side": "buy"},
+				{"id": 1, "instrument": "EUR_USD", "units": 1, "side": "buy"}]}`},
+			{StatusCode: 200, Body: `{"id": 2, "instrument": "EUR_USD", "stopLoss": 1.05}`},
+			{StatusCode: 404, Body: `{"code": 1, "message": "Trade not found"}`},
+		},
+	}
+	client := newStubClient(c, &tr)
+	client.SelectAccount(1)
+
+	_, err := client.ModifyTradesForInstrument("eur_usd")
+	c.Assert(err, check.FitsTypeOf, &oanda.ValidationError{})
+	c.Assert(tr.Requests, check.HasLen, 0)
+
+	results, err := client.ModifyTradesForInstrument("eur/usd", oanda.StopLoss(1.05))
+	c.Assert(tr.Requests, check.HasLen, 3)
+	c.Assert(tr.Requests[0].URL.Query().Get("instrument"), check.Equals, "EUR_USD")
+	c.Assert(tr.Requests[1].Method, check.Equals, "PATCH")
+	c.Assert(tr.Requests[1].URL.Path, check.Equals, "/v1/accounts/1/trades/2")
+	c.Assert(tr.Requests[2].URL.Path, check.Equals, "/v1/accounts/1/trades/1")
+
+	c.Assert(results, check.HasLen, 2)
+	c.Assert(results[0].TradeId, check.Equals, oanda.Id(2))
+	c.Assert(results[0].Err, check.IsNil)
+	c.Assert(results[0].Trade.StopLoss, check.Equals, 1.05)
+	c.Assert(results[1].TradeId, check.Equals, oanda.Id(1))
+	c.Assert(results[1].Err, check.NotNil)
+
+	c.Assert(err, check.FitsTypeOf, &oanda.ModifyTradesError{})
+	c.Assert(err.(*oanda.ModifyTradesError).Failed, check.HasLen, 1)
+	c.Assert(err.(*oanda.ModifyTradesError).Failed[0].TradeId, check.Equals, oanda.Id(1))
+}
+
+func (s *TradesSuite) TestTradesListShapes(c *check.C) {
+	for _, tc := range []struct {
+		body string
+		n    int
+	}{
+		{`{"trades": [{"id": 1, "instrument": "EUR_USD"}, {"id": 2, "instrument": "EUR_USD"}]}`, 2},
+		{`[{"id": 1, "instrument": "EUR_USD"}, {"id": 2, "instrument": "EUR_USD"}]`, 2},
+		{`{"trades": []}`, 0},
+		{`[]`, 0},
+	} {
+		client := newStubClient(c, &stubTransport{StatusCode: 200, Body: tc.body})
+		client.SelectAccount(1)
+
+		trades, err := client.Trades()
+		c.Assert(err, check.IsNil, check.Commentf(tc.body))
+		c.Assert(trades, check.HasLen, tc.n, check.Commentf(tc.body))
+		for i, t := range trades {
+			c.Assert(t.TradeId, check.Equals, oanda.Id(i+1))
+			c.Assert(t.Instrument, check.Equals, "EUR_USD")
+		}
+	}
+
+	for _, body := range []string{
+		`{"trades": {"id": 1, "instrument": "EUR_USD"}}`,
+		`{"id": 1, "instrument": "EUR_USD"}`,
+		`{"nextPage": "https://api-fxpractice.oanda.com/v1/accounts/1/trades?maxId=1"}`,
+	} {
+		client := newStubClient(c, &stubTransport{StatusCode: 200, Body: body})
+		client.SelectAccount(1)
+
+		_, err := client.Trades()
+		c.Assert(err, check.ErrorMatches, "Unexpected JSON document for list trades: .*",
+			check.Commentf(body))
+	}
+
+	client := newStubClient(c, &stubTransport{StatusCode: 200,
+		Body: `[{"id": 3, "instrument": "EUR_USD", "type": "limit"}]`})
+	client.SelectAccount(1)
+	orders, err := client.Orders()
+	c.Assert(err, check.IsNil)
+	c.Assert(orders, check.HasLen, 1)
+	c.Assert(orders[0].OrderId, check.Equals, oanda.Id(3))
+}
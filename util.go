@@ -15,6 +15,8 @@
 package oanda
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -22,6 +24,88 @@ import (
 	"time"
 )
 
+// NormalizeInstrument converts an instrument name such as "eur/usd", "EURUSD", "eur-usd" or
+// "eur_usd" into the canonical form "EUR_USD".  An error is returned if s is not a valid
+// instrument name.
+func NormalizeInstrument(s string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	name = strings.NewReplacer("/", "_", "-", "_", " ", "_").Replace(name)
+
+	parts := strings.Split(name, "_")
+	if len(parts) == 1 && len(name) == 6 {
+		parts = []string{name[:3], name[3:]}
+	}
+	if len(parts) != 2 || !isInstrumentPart(parts[0]) || !isInstrumentPart(parts[1]) {
+		return "", &ValidationError{
+			Field:  "instrument",
+			Reason: fmt.Sprintf("Invalid instrument %q.", s),
+		}
+	}
+	return parts[0] + "_" + parts[1], nil
+}
+
+// instrumentKey returns the canonical name of instrument, as returned by NormalizeInstrument, for
+// use in lookups and in optional arguments where an error can not be reported.  An invalid
+// instrument name is returned in upper case so that it simply matches nothing.
+func instrumentKey(instrument string) string {
+	if name, err := NormalizeInstrument(instrument); err == nil {
+		return name
+	}
+	return strings.ToUpper(instrument)
+}
+
+// normalizeInstruments applies NormalizeInstrument to every instrument in instrs.
+func normalizeInstruments(instrs []string) ([]string, error) {
+	normalized := make([]string, len(instrs))
+	for i, instr := range instrs {
+		var err error
+		if normalized[i], err = NormalizeInstrument(instr); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
+}
+
+func isInstrumentPart(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeList decodes a list into the slice to which v points.  The list is accepted in either of
+// the shapes in which Oanda returns lists: wrapped in an object under key or as a bare array.
+func decodeList(data []byte, key string, v interface{}) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return nil
+	case data[0] == '[':
+		return json.Unmarshal(data, v)
+	case data[0] != '{':
+		return fmt.Errorf("Unexpected JSON document for list %s: %.32s", key, data)
+	}
+
+	obj := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	list, ok := obj[key]
+	if !ok {
+		return fmt.Errorf("Unexpected JSON document for list %s: %.32s", key, data)
+	}
+	list = bytes.TrimSpace(list)
+	if len(list) > 0 && list[0] == '{' {
+		return fmt.Errorf("Unexpected JSON document for list %s: %.32s", key, list)
+	}
+	return decodeList(list, key, v)
+}
+
 type optionalArgs url.Values
 
 func (oa optionalArgs) SetInt(k string, n int) {
@@ -37,7 +121,7 @@ func (oa optionalArgs) SetId(k string, id Id) {
 }
 
 func (oa optionalArgs) SetFloat(k string, f float64) {
-	url.Values(oa).Set(k, strconv.FormatFloat(f, 'f', -1, 64))
+	url.Values(oa).Set(k, formatDecimal(f))
 }
 
 func (oa optionalArgs) SetIdArray(k string, ia []Id) {
@@ -98,3 +182,24 @@ func (t Time) String() string {
 func (t Time) IsZero() bool {
 	return t == ""
 }
+
+// maxRequestDecimals is the number of decimals to which floating point values are rounded in
+// requests.  It exceeds the precision of every instrument.
+const maxRequestDecimals = 8
+
+// formatDecimal formats f in plain decimal notation, never with an exponent, rounded to
+// maxRequestDecimals decimals so that floating point noise such as in 1.1 + 0.2 is not sent.
+// Values other than zero that are too small to survive the rounding are sent with all their
+// digits instead of as 0.
+func formatDecimal(f float64) string {
+	s := strconv.FormatFloat(f, 'f', maxRequestDecimals, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "0" || s == "-0" {
+		if f == 0 {
+			return "0"
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return s
+}
@@ -1,6 +1,7 @@
 package oanda_test
 
 import (
+	"math"
 	"time"
 
 	"github.com/santegoeds/oanda"
@@ -38,3 +39,58 @@ func (s *UtilSuite) TestTimeTime(c *check.C) {
 
 	c.Assert(s.Time.Time(), check.Equals, expected)
 }
+
+func (s *UtilSuite) TestNormalizeInstrument(c *check.C) {
+	for _, name := range []string{"eur/usd", "EURUSD", "eur_usd", "Eur-Usd", " EUR_USD "} {
+		instr, err := oanda.NormalizeInstrument(name)
+		c.Assert(err, check.IsNil)
+		c.Assert(instr, check.Equals, "EUR_USD")
+	}
+
+	instr, err := oanda.NormalizeInstrument("spx500_usd")
+	c.Assert(err, check.IsNil)
+	c.Assert(instr, check.Equals, "SPX500_USD")
+
+	for _, name := range []string{"", "EUR", "EURUSDX", "EUR_USD_GBP", "_USD", "EUR.USD"} {
+		_, err := oanda.NormalizeInstrument(name)
+		c.Assert(err, check.NotNil, check.Commentf("instrument %q", name))
+	}
+}
+
+func (s *UtilSuite) TestInstrumentLookupsAreNormalized(c *check.C) {
+	positions := oanda.Positions{{Side: "buy", Instrument: "EUR_USD", Units: 100, AvgPrice: 1.1}}
+	for _, name := range []string{"eur/usd", "EURUSD", "eur_usd"} {
+		p, ok := positions.ForInstrument(name)
+		c.Assert(ok, check.Equals, true, check.Commentf("instrument %q", name))
+		c.Assert(p.Instrument, check.Equals, "EUR_USD")
+	}
+
+	trades := oanda.Trades{
+		{Instrument: "EUR_USD", Units: 100, Price: 1.1},
+		{Instrument: "EUR_USD", Units: 300, Price: 1.2},
+	}
+	_, units := trades.WeightedAvgPrice("eurusd")
+	c.Assert(units, check.Equals, 400)
+
+	base, quote := oanda.SplitInstrument("eur/usd")
+	c.Assert(base, check.Equals, "EUR")
+	c.Assert(quote, check.Equals, "USD")
+
+	tr := stubTransport{StatusCode: 200, Body: `{"trades": []}`}
+	client := newStubClient(c, &tr)
+	_, err := client.Trades(oanda.Instrument("eur/usd"))
+	c.Assert(err, check.IsNil)
+	c.Assert(tr.Requests[0].URL.Query().Get("instrument"), check.Equals, "EUR_USD")
+
+	tick := oanda.PriceTick{Time: "1400000000000000", Bid: 1.1, Ask: 1.1002}
+	st := oanda.NewSpreadTracker(10)
+	st.Update("eur_usd", tick)
+	_, avg, _ := st.SpreadStats("EUR/USD")
+	c.Assert(math.IsNaN(avg), check.Equals, false)
+
+	ct, err := oanda.NewCandleTracker(oanda.M1)
+	c.Assert(err, check.IsNil)
+	ct.Update("eur_usd", tick)
+	_, ok := ct.Candle("EURUSD")
+	c.Assert(ok, check.Equals, true)
+}